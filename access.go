@@ -0,0 +1,171 @@
+package s3fs
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type (
+	CORSRule struct {
+		ID             string   `json:"id,omitempty"`
+		AllowedOrigins []string `json:"allowedOrigins"`
+		AllowedMethods []string `json:"allowedMethods"`
+		AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+		ExposeHeaders  []string `json:"exposeHeaders,omitempty"`
+		MaxAgeSeconds  int32    `json:"maxAgeSeconds,omitempty"`
+	}
+	PublicAccessBlock struct {
+		BlockPublicAcls       bool `json:"blockPublicAcls"`
+		IgnorePublicAcls      bool `json:"ignorePublicAcls"`
+		BlockPublicPolicy     bool `json:"blockPublicPolicy"`
+		RestrictPublicBuckets bool `json:"restrictPublicBuckets"`
+	}
+)
+
+// BrowserUploadCORS returns a CORS rule that lets browsers on the given
+// origins upload with presigned PUT or POST requests and read the ETag of
+// the result, which multipart uploads need to complete.
+func BrowserUploadCORS(origins ...string) CORSRule {
+	return CORSRule{
+		ID:             "browser-upload",
+		AllowedOrigins: origins,
+		AllowedMethods: []string{"GET", "HEAD", "PUT", "POST"},
+		AllowedHeaders: []string{"*"},
+		ExposeHeaders:  []string{"ETag", "x-amz-version-id"},
+		MaxAgeSeconds:  3000,
+	}
+}
+
+func (s3fs *S3FS) GetCORS() ([]CORSRule, error) {
+	output, err := s3fs.s3.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	if errorCode(err) == "NoSuchCORSConfiguration" {
+		return []CORSRule{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]CORSRule, 0, len(output.CORSRules))
+	for _, rule := range output.CORSRules {
+		rules = append(rules, CORSRule{
+			ID:             aws.ToString(rule.ID),
+			AllowedOrigins: rule.AllowedOrigins,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedHeaders: rule.AllowedHeaders,
+			ExposeHeaders:  rule.ExposeHeaders,
+			MaxAgeSeconds:  aws.ToInt32(rule.MaxAgeSeconds),
+		})
+	}
+	return rules, nil
+}
+
+// PutCORS replaces the CORS configuration of the bucket. Passing no rules
+// deletes it.
+func (s3fs *S3FS) PutCORS(rules ...CORSRule) error {
+	if len(rules) == 0 {
+		return s3fs.DeleteCORS()
+	}
+	corsRules := make([]types.CORSRule, 0, len(rules))
+	for _, rule := range rules {
+		corsRule := types.CORSRule{
+			AllowedOrigins: rule.AllowedOrigins,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedHeaders: rule.AllowedHeaders,
+			ExposeHeaders:  rule.ExposeHeaders,
+		}
+		if rule.ID != "" {
+			corsRule.ID = aws.String(rule.ID)
+		}
+		if rule.MaxAgeSeconds > 0 {
+			corsRule.MaxAgeSeconds = aws.Int32(rule.MaxAgeSeconds)
+		}
+		corsRules = append(corsRules, corsRule)
+	}
+	_, err := s3fs.s3.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		CORSConfiguration: &types.CORSConfiguration{
+			CORSRules: corsRules,
+		},
+	})
+	return err
+}
+
+func (s3fs *S3FS) DeleteCORS() error {
+	_, err := s3fs.s3.DeleteBucketCors(ctx, &s3.DeleteBucketCorsInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	return err
+}
+
+// GetPolicy returns the bucket policy JSON, or an empty string when the
+// bucket has no policy.
+func (s3fs *S3FS) GetPolicy() (string, error) {
+	output, err := s3fs.s3.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	if errorCode(err) == "NoSuchBucketPolicy" {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(output.Policy), nil
+}
+
+func (s3fs *S3FS) PutPolicy(policy string) error {
+	_, err := s3fs.s3.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Policy: aws.String(policy),
+	})
+	return err
+}
+
+func (s3fs *S3FS) DeletePolicy() error {
+	_, err := s3fs.s3.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	return err
+}
+
+// GetPublicAccessBlock returns the public access block of the bucket. A
+// bucket without one reports every setting as false.
+func (s3fs *S3FS) GetPublicAccessBlock() (*PublicAccessBlock, error) {
+	output, err := s3fs.s3.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	if errorCode(err) == "NoSuchPublicAccessBlockConfiguration" {
+		return &PublicAccessBlock{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	block := output.PublicAccessBlockConfiguration
+	return &PublicAccessBlock{
+		BlockPublicAcls:       aws.ToBool(block.BlockPublicAcls),
+		IgnorePublicAcls:      aws.ToBool(block.IgnorePublicAcls),
+		BlockPublicPolicy:     aws.ToBool(block.BlockPublicPolicy),
+		RestrictPublicBuckets: aws.ToBool(block.RestrictPublicBuckets),
+	}, nil
+}
+
+func (s3fs *S3FS) PutPublicAccessBlock(block PublicAccessBlock) error {
+	_, err := s3fs.s3.PutPublicAccessBlock(ctx, &s3.PutPublicAccessBlockInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		PublicAccessBlockConfiguration: &types.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(block.BlockPublicAcls),
+			IgnorePublicAcls:      aws.Bool(block.IgnorePublicAcls),
+			BlockPublicPolicy:     aws.Bool(block.BlockPublicPolicy),
+			RestrictPublicBuckets: aws.Bool(block.RestrictPublicBuckets),
+		},
+	})
+	return err
+}
+
+func (s3fs *S3FS) DeletePublicAccessBlock() error {
+	_, err := s3fs.s3.DeletePublicAccessBlock(ctx, &s3.DeletePublicAccessBlockInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	return err
+}
@@ -0,0 +1,28 @@
+package s3fs
+
+import (
+	"testing"
+)
+
+func TestBrowserUploadCORS(t *testing.T) {
+	rule := BrowserUploadCORS("https://example.com")
+	if len(rule.AllowedOrigins) != 1 || rule.AllowedOrigins[0] != "https://example.com" {
+		t.Fatal("invalid origins:", rule.AllowedOrigins)
+	}
+	methods := map[string]bool{}
+	for _, method := range rule.AllowedMethods {
+		methods[method] = true
+	}
+	if !methods["PUT"] || !methods["POST"] {
+		t.Fatal("upload methods not allowed:", rule.AllowedMethods)
+	}
+	exposed := false
+	for _, header := range rule.ExposeHeaders {
+		if header == "ETag" {
+			exposed = true
+		}
+	}
+	if !exposed {
+		t.Fatal("ETag not exposed:", rule.ExposeHeaders)
+	}
+}
@@ -0,0 +1,169 @@
+package s3fs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Snapshot is a read-only view of the bucket as it was at a point in time.
+// Every key resolves to the version that was current at that time.
+type Snapshot struct {
+	fs *S3FS
+	at time.Time
+}
+
+var ErrReadOnly = fmt.Errorf("read-only view: %w", os.ErrPermission)
+
+// AsOf returns a read-only view of the bucket as it was at t. The bucket
+// must have versioning enabled for the view to reach past the present.
+func (s3fs *S3FS) AsOf(t time.Time) *Snapshot {
+	return &Snapshot{
+		fs: s3fs,
+		at: t,
+	}
+}
+
+func (snapshot *Snapshot) At() time.Time {
+	return snapshot.at
+}
+
+func (snapshot *Snapshot) List(key string) *[]FileInfo {
+	live, err := snapshot.live(key)
+	if err != nil {
+		return nil
+	}
+
+	prefix := snapshot.fs.pathOf(snapshot.fs.getKey(key))
+	dirs := map[string]bool{}
+	fileList := make([]FileInfo, 0)
+	for _, v := range live {
+		rest := strings.TrimPrefix(v.Path, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			path := prefix + rest[:i+1]
+			if path == "/" || dirs[path] {
+				continue
+			}
+			dirs[path] = true
+			fileList = append(fileList, FileInfo{
+				Type: Directory,
+				Name: nameOf(path),
+				Path: path,
+			})
+			continue
+		}
+		if v.Path == prefix || v.Path == "/" {
+			continue
+		}
+		fileList = append(fileList, FileInfo{
+			Type: File,
+			Name: v.Name,
+			Path: v.Path,
+			Size: v.Size,
+		})
+	}
+	sort.SliceStable(fileList, func(i, j int) bool {
+		return fileList[i].Type < fileList[j].Type
+	})
+	return &fileList
+}
+
+func (snapshot *Snapshot) Get(key string) (*io.ReadCloser, error) {
+	v, err := snapshot.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.fs.GetVersion(key, v.VersionID)
+}
+
+func (snapshot *Snapshot) Info(key string) *s3.HeadObjectOutput {
+	v, err := snapshot.resolve(key)
+	if err != nil {
+		return nil
+	}
+	return snapshot.fs.InfoVersion(key, v.VersionID)
+}
+
+// Walk calls fn for every file and directory marker that existed under key
+// at the time of the snapshot, in key order.
+func (snapshot *Snapshot) Walk(key string, fn func(FileInfo) error) error {
+	live, err := snapshot.live(key)
+	if err != nil {
+		return err
+	}
+	for _, v := range live {
+		if v.Path == "/" {
+			continue
+		}
+		if err := fn(snapshot.fs.fileInfo(snapshot.fs.getKey(v.Path), v.Size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (snapshot *Snapshot) MkDir(key string) error {
+	return ErrReadOnly
+}
+
+func (snapshot *Snapshot) Put(key string, body io.ReadCloser, contentType string) error {
+	return ErrReadOnly
+}
+
+func (snapshot *Snapshot) Delete(key string) error {
+	return ErrReadOnly
+}
+
+func (snapshot *Snapshot) Copy(src string, dest string, metadata map[string]string) error {
+	return ErrReadOnly
+}
+
+func (snapshot *Snapshot) Move(src string, dest string) error {
+	return ErrReadOnly
+}
+
+// live returns the versions under prefix that were current at the time of
+// the snapshot, sorted by path.
+func (snapshot *Snapshot) live(prefix string) ([]VersionInfo, error) {
+	history, err := snapshot.fs.versionHistory(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return liveAt(history, snapshot.at), nil
+}
+
+func (snapshot *Snapshot) resolve(key string) (*VersionInfo, error) {
+	versions, err := snapshot.fs.ListVersions(key)
+	if err != nil {
+		return nil, err
+	}
+	v := versionAt(*versions, snapshot.at)
+	if v == nil || v.IsDeleteMarker {
+		return nil, &types.NoSuchKey{
+			Message: aws.String("key did not exist at " + snapshot.at.Format(time.RFC3339)),
+		}
+	}
+	return v, nil
+}
+
+func liveAt(history map[string][]VersionInfo, at time.Time) []VersionInfo {
+	live := make([]VersionInfo, 0, len(history))
+	for _, versions := range history {
+		v := versionAt(versions, at)
+		if v == nil || v.IsDeleteMarker {
+			continue
+		}
+		live = append(live, *v)
+	}
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].Path < live[j].Path
+	})
+	return live
+}
@@ -0,0 +1,51 @@
+package s3fs
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLiveAt(t *testing.T) {
+	now := time.Now()
+	history := map[string][]VersionInfo{
+		"/b": {
+			{Path: "/b", VersionID: "v2", LastModified: now, IsLatest: true},
+			{Path: "/b", VersionID: "v1", LastModified: now.Add(-time.Hour)},
+		},
+		"/a": {
+			{Path: "/a", VersionID: "marker", LastModified: now, IsLatest: true, IsDeleteMarker: true},
+			{Path: "/a", VersionID: "v1", LastModified: now.Add(-time.Hour)},
+		},
+		"/c": {
+			{Path: "/c", VersionID: "v1", LastModified: now, IsLatest: true},
+		},
+	}
+
+	live := liveAt(history, now.Add(-time.Minute))
+	if len(live) != 2 {
+		t.Fatal("invalid live versions:", live)
+	}
+	if live[0].Path != "/a" || live[0].VersionID != "v1" {
+		t.Fatal("invalid live version:", live[0])
+	}
+	if live[1].Path != "/b" || live[1].VersionID != "v1" {
+		t.Fatal("invalid live version:", live[1])
+	}
+
+	live = liveAt(history, now)
+	if len(live) != 2 || live[0].Path != "/b" || live[1].Path != "/c" {
+		t.Fatal("invalid live versions:", live)
+	}
+}
+
+func TestSnapshot_ReadOnly(t *testing.T) {
+	snapshot := fs.AsOf(time.Now())
+	if err := snapshot.MkDir("/dir"); !errors.Is(err, os.ErrPermission) {
+		t.Fatal("mkdir should be denied:", err)
+	}
+	if err := snapshot.Delete("/file"); !errors.Is(err, os.ErrPermission) {
+		t.Fatal("delete should be denied:", err)
+	}
+}
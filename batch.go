@@ -0,0 +1,264 @@
+package s3fs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reportPrefix is the reserved directory, inside the tenant, holding batch
+// completion reports.
+const reportPrefix = reservedPrefix + "reports/"
+
+type BatchKind string
+
+const (
+	// BatchCopy copies each key to its Dest, or into BatchOp.Dest.
+	BatchCopy BatchKind = "copy"
+	// BatchDelete deletes each key, or the given version of it.
+	BatchDelete BatchKind = "delete"
+	// BatchTag replaces the tags of each key with BatchOp.Tags.
+	BatchTag BatchKind = "tag"
+	// BatchRestore makes the given version of each key current again, or
+	// undeletes keys listed without a version.
+	BatchRestore BatchKind = "restore"
+)
+
+type (
+	BatchOp struct {
+		Kind BatchKind
+		// Dest is the directory keys are copied into when a manifest entry
+		// has no destination of its own.
+		Dest        string
+		CopyOptions CopyOptions
+		Tags        map[string]string
+		// Concurrency defaults to 16 keys at once.
+		Concurrency int
+		// Retries is how many times a failed key is retried. It defaults to
+		// 3; a negative value disables retries.
+		Retries int
+		// ReportKey is where the completion report is written. It defaults
+		// to a report object under /.s3fs/reports/.
+		ReportKey string
+	}
+	// BatchEntry is a manifest entry. A CSV manifest lists one entry per
+	// row as key[,versionId[,dest]]; a JSON lines manifest one object per
+	// line with these fields.
+	BatchEntry struct {
+		Key       string `json:"key"`
+		VersionID string `json:"versionId,omitempty"`
+		Dest      string `json:"dest,omitempty"`
+	}
+	BatchResult struct {
+		BatchEntry
+		Succeeded bool   `json:"succeeded"`
+		Error     string `json:"error,omitempty"`
+		Attempts  int    `json:"attempts"`
+	}
+	BatchReport struct {
+		ID        string        `json:"id"`
+		Kind      BatchKind     `json:"kind"`
+		ReportKey string        `json:"reportKey"`
+		Started   time.Time     `json:"started"`
+		Finished  time.Time     `json:"finished"`
+		Total     int           `json:"total"`
+		Succeeded int           `json:"succeeded"`
+		Failed    int           `json:"failed"`
+		Results   []BatchResult `json:"results"`
+	}
+)
+
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+// RunBatch applies op to every key listed in manifest, which is read as
+// JSON lines when it starts with "{" and as CSV otherwise. Keys are
+// processed concurrently as the manifest is read, and failed keys are
+// retried with backoff. The completion report is written to the bucket
+// and returned; keys that failed are reported rather than returned as an
+// error. A malformed manifest stops the batch once the entries before it
+// have been applied; the report of those entries is still written and
+// returned along with ErrInvalidManifest.
+func (s3fs *S3FS) RunBatch(manifest io.Reader, op BatchOp) (*BatchReport, error) {
+	id := newID()
+	report := &BatchReport{
+		ID:        id,
+		Kind:      op.Kind,
+		ReportKey: op.ReportKey,
+		Started:   time.Now().UTC(),
+		Results:   []BatchResult{},
+	}
+	if report.ReportKey == "" {
+		report.ReportKey = reportPrefix + id + ".json"
+	}
+	concurrency := op.Concurrency
+	if concurrency <= 0 {
+		concurrency = requestConcurrency
+	}
+
+	var mu sync.Mutex
+	wg := &sync.WaitGroup{}
+	sem := make(chan struct{}, concurrency)
+	manifestErr := readManifest(manifest, func(entry BatchEntry) {
+		mu.Lock()
+		i := len(report.Results)
+		report.Results = append(report.Results, BatchResult{BatchEntry: entry})
+		mu.Unlock()
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			attempts, err := s3fs.batchEntry(entry, op)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Results[i].Attempts = attempts
+			report.Results[i].Succeeded = err == nil
+			if err != nil {
+				report.Results[i].Error = err.Error()
+			}
+		}()
+	})
+	wg.Wait()
+
+	report.Finished = time.Now().UTC()
+	report.Total = len(report.Results)
+	for _, result := range report.Results {
+		if result.Succeeded {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	if err := s3fs.Put(report.ReportKey, io.NopCloser(bytes.NewReader(data)), "application/json"); err != nil {
+		return nil, errors.Join(manifestErr, err)
+	}
+	return report, manifestErr
+}
+
+// batchEntry applies op to entry, retrying failures that may be transient.
+// It returns the number of attempts made.
+func (s3fs *S3FS) batchEntry(entry BatchEntry, op BatchOp) (int, error) {
+	retries := op.Retries
+	if retries == 0 {
+		retries = 3
+	}
+	backoff := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := s3fs.applyBatchOp(entry, op)
+		if err == nil || attempt > retries || !retryable(err) {
+			return attempt, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (s3fs *S3FS) applyBatchOp(entry BatchEntry, op BatchOp) error {
+	switch op.Kind {
+	case BatchCopy:
+		dest := entry.Dest
+		if dest == "" {
+			if op.Dest == "" {
+				return errors.New("no destination")
+			}
+			dest = strings.TrimSuffix(op.Dest, "/") + "/" + strings.TrimPrefix(entry.Key, "/")
+		}
+		return s3fs.singleCopy(entry.Key, dest, op.CopyOptions)
+	case BatchDelete:
+		if entry.VersionID != "" {
+			return s3fs.DeleteVersion(entry.Key, entry.VersionID)
+		}
+		return s3fs.SingleDelete(entry.Key)
+	case BatchTag:
+		return s3fs.SetTags(entry.Key, op.Tags)
+	case BatchRestore:
+		if entry.VersionID != "" {
+			return s3fs.RestoreVersion(entry.Key, entry.VersionID)
+		}
+		return s3fs.Undelete(entry.Key)
+	}
+	return errors.New("unknown batch operation: " + string(op.Kind))
+}
+
+// retryable reports whether err may succeed when retried.
+func retryable(err error) bool {
+	if errors.Is(err, ErrPreconditionFailed) || errors.Is(err, ErrNotDeleted) || errors.Is(err, ErrObjectLocked) {
+		return false
+	}
+	switch errorCode(err) {
+	case "NoSuchKey", "NotFound", "NoSuchVersion", "AccessDenied", "InvalidArgument", "InvalidRequest":
+		return false
+	}
+	return true
+}
+
+// readManifest calls fn for each entry of manifest as it is read.
+func readManifest(manifest io.Reader, fn func(BatchEntry)) error {
+	r := bufio.NewReader(manifest)
+	first, err := r.Peek(1)
+	for err == nil && strings.TrimSpace(string(first)) == "" {
+		if _, err = r.ReadByte(); err == nil {
+			first, err = r.Peek(1)
+		}
+	}
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if first[0] == '{' {
+		decoder := json.NewDecoder(r)
+		for {
+			entry := BatchEntry{}
+			err := decoder.Decode(&entry)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.Join(ErrInvalidManifest, err)
+			}
+			if entry.Key == "" {
+				return errors.Join(ErrInvalidManifest, errors.New("entry without key"))
+			}
+			fn(entry)
+		}
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Join(ErrInvalidManifest, err)
+		}
+		if len(record) > 3 || record[0] == "" {
+			return errors.Join(ErrInvalidManifest, errors.New("expected key[,versionId[,dest]]"))
+		}
+		entry := BatchEntry{Key: record[0]}
+		if len(record) > 1 {
+			entry.VersionID = record[1]
+		}
+		if len(record) > 2 {
+			entry.Dest = record[2]
+		}
+		fn(entry)
+	}
+}
@@ -0,0 +1,63 @@
+package s3fs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestReadManifest(t *testing.T) {
+	read := func(manifest string) ([]BatchEntry, error) {
+		entries := []BatchEntry{}
+		err := readManifest(strings.NewReader(manifest), func(entry BatchEntry) {
+			entries = append(entries, entry)
+		})
+		return entries, err
+	}
+	t.Run("csv", func(st *testing.T) {
+		entries, err := read("/a\n/b,v1\n/c,,/d\n")
+		if err != nil {
+			st.Fatal("read error:", err)
+		}
+		if len(entries) != 3 || entries[1].VersionID != "v1" || entries[2].Dest != "/d" {
+			st.Fatal("entries error:", entries)
+		}
+	})
+	t.Run("json lines", func(st *testing.T) {
+		entries, err := read("\n{\"key\":\"/a\"}\n{\"key\":\"/b\",\"versionId\":\"v1\"}\n")
+		if err != nil {
+			st.Fatal("read error:", err)
+		}
+		if len(entries) != 2 || entries[1].VersionID != "v1" {
+			st.Fatal("entries error:", entries)
+		}
+	})
+	t.Run("empty", func(st *testing.T) {
+		entries, err := read("")
+		if err != nil || len(entries) != 0 {
+			st.Fatal("read error:", err, entries)
+		}
+	})
+	t.Run("invalid", func(st *testing.T) {
+		if _, err := read("/a,v1,/b,extra\n"); !errors.Is(err, ErrInvalidManifest) {
+			st.Fatal("csv error:", err)
+		}
+		if _, err := read("{\"versionId\":\"v1\"}\n"); !errors.Is(err, ErrInvalidManifest) {
+			st.Fatal("json error:", err)
+		}
+	})
+}
+
+func TestRetryable(t *testing.T) {
+	if retryable(&smithy.GenericAPIError{Code: "NoSuchKey"}) {
+		t.Fatal("missing keys should not be retried")
+	}
+	if retryable(ErrPreconditionFailed) {
+		t.Fatal("failed preconditions should not be retried")
+	}
+	if !retryable(&smithy.GenericAPIError{Code: "SlowDown"}) {
+		t.Fatal("throttling should be retried")
+	}
+}
@@ -0,0 +1,266 @@
+package s3fs
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type (
+	BucketInfo struct {
+		Name         string    `json:"name"`
+		CreationDate time.Time `json:"creationDate"`
+	}
+	EmptyProgress struct {
+		// Deleted counts removed objects, versions and delete markers.
+		Deleted int64
+		// Aborted counts aborted multipart uploads.
+		Aborted int64
+	}
+	BucketEncryption struct {
+		// Algorithm is types.ServerSideEncryptionAes256 for SSE-S3 or
+		// types.ServerSideEncryptionAwsKms for SSE-KMS.
+		Algorithm types.ServerSideEncryption
+		KMSKeyID  string
+		// BucketKey enables S3 Bucket Keys to reduce SSE-KMS request costs.
+		BucketKey bool
+	}
+)
+
+func (s3fs *S3FS) ListBuckets() (*[]BucketInfo, error) {
+	buckets := make([]BucketInfo, 0)
+	var continuationToken *string
+	for {
+		list, err := s3fs.s3.ListBuckets(ctx, &s3.ListBucketsInput{
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, val := range list.Buckets {
+			buckets = append(buckets, BucketInfo{
+				Name:         aws.ToString(val.Name),
+				CreationDate: aws.ToTime(val.CreationDate),
+			})
+		}
+		if aws.ToString(list.ContinuationToken) == "" {
+			return &buckets, nil
+		}
+		continuationToken = list.ContinuationToken
+	}
+}
+
+func (s3fs *S3FS) BucketExists(name string) (bool, error) {
+	_, err := s3fs.s3.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(name),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) || errorCode(err) == "NoSuchBucket" {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// emptyBucket removes every object version, delete marker and incomplete
+// multipart upload from bucket.
+func (s3fs *S3FS) emptyBucket(bucket string, progress func(EmptyProgress)) error {
+	report := func(p EmptyProgress) {
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	var p EmptyProgress
+	var keyMarker, versionIDMarker *string
+	for {
+		list, err := s3fs.s3.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:          aws.String(bucket),
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionIDMarker,
+		})
+		if err != nil {
+			return err
+		}
+
+		objects := []types.ObjectIdentifier{}
+		for _, val := range list.Versions {
+			objects = append(objects, types.ObjectIdentifier{
+				Key:       val.Key,
+				VersionId: val.VersionId,
+			})
+		}
+		for _, val := range list.DeleteMarkers {
+			objects = append(objects, types.ObjectIdentifier{
+				Key:       val.Key,
+				VersionId: val.VersionId,
+			})
+		}
+		if err := s3fs.deleteObjects(bucket, objects); err != nil {
+			return err
+		}
+		p.Deleted += int64(len(objects))
+		report(p)
+
+		if !aws.ToBool(list.IsTruncated) {
+			break
+		}
+		keyMarker = list.NextKeyMarker
+		versionIDMarker = list.NextVersionIdMarker
+	}
+
+	var uploadIDMarker *string
+	keyMarker = nil
+	for {
+		list, err := s3fs.s3.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+			Bucket:         aws.String(bucket),
+			KeyMarker:      keyMarker,
+			UploadIdMarker: uploadIDMarker,
+		})
+		if err != nil {
+			return err
+		}
+		for _, upload := range list.Uploads {
+			_, err := s3fs.s3.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(bucket),
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			})
+			if err != nil {
+				return err
+			}
+			p.Aborted++
+		}
+		if len(list.Uploads) > 0 {
+			report(p)
+		}
+
+		if !aws.ToBool(list.IsTruncated) {
+			return nil
+		}
+		keyMarker = list.NextKeyMarker
+		uploadIDMarker = list.NextUploadIdMarker
+	}
+}
+
+// GetEncryption returns the default encryption of the bucket, or nil when
+// none is configured.
+func (s3fs *S3FS) GetEncryption() (*BucketEncryption, error) {
+	output, err := s3fs.s3.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	if errorCode(err) == "ServerSideEncryptionConfigurationNotFoundError" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range output.ServerSideEncryptionConfiguration.Rules {
+		if rule.ApplyServerSideEncryptionByDefault == nil {
+			continue
+		}
+		return &BucketEncryption{
+			Algorithm: rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm,
+			KMSKeyID:  aws.ToString(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID),
+			BucketKey: aws.ToBool(rule.BucketKeyEnabled),
+		}, nil
+	}
+	return nil, nil
+}
+
+func (s3fs *S3FS) PutEncryption(encryption BucketEncryption) error {
+	return s3fs.putBucketEncryption(s3fs.config.Bucket, encryption)
+}
+
+func (s3fs *S3FS) DeleteEncryption() error {
+	_, err := s3fs.s3.DeleteBucketEncryption(ctx, &s3.DeleteBucketEncryptionInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	return err
+}
+
+func (s3fs *S3FS) GetBucketTags() (map[string]string, error) {
+	output, err := s3fs.s3.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	if errorCode(err) == "NoSuchTagSet" {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tagMap(output.TagSet), nil
+}
+
+// PutBucketTags replaces the tags of the bucket. Passing no tags deletes
+// them.
+func (s3fs *S3FS) PutBucketTags(tags map[string]string) error {
+	if len(tags) == 0 {
+		return s3fs.DeleteBucketTags()
+	}
+	return s3fs.putBucketTags(s3fs.config.Bucket, tags)
+}
+
+func (s3fs *S3FS) DeleteBucketTags() error {
+	_, err := s3fs.s3.DeleteBucketTagging(ctx, &s3.DeleteBucketTaggingInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	return err
+}
+
+func (s3fs *S3FS) putBucketEncryption(bucket string, encryption BucketEncryption) error {
+	rule := types.ServerSideEncryptionRule{
+		ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+			SSEAlgorithm: encryption.Algorithm,
+		},
+		BucketKeyEnabled: aws.Bool(encryption.BucketKey),
+	}
+	if encryption.KMSKeyID != "" {
+		rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID = aws.String(encryption.KMSKeyID)
+	}
+	_, err := s3fs.s3.PutBucketEncryption(ctx, &s3.PutBucketEncryptionInput{
+		Bucket: aws.String(bucket),
+		ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{
+			Rules: []types.ServerSideEncryptionRule{rule},
+		},
+	})
+	return err
+}
+
+func (s3fs *S3FS) putBucketTags(bucket string, tags map[string]string) error {
+	_, err := s3fs.s3.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket: aws.String(bucket),
+		Tagging: &types.Tagging{
+			TagSet: tagSet(tags),
+		},
+	})
+	return err
+}
+
+func tagSet(tags map[string]string) []types.Tag {
+	set := make([]types.Tag, 0, len(tags))
+	for k, v := range tags {
+		set = append(set, types.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		})
+	}
+	sort.Slice(set, func(i, j int) bool {
+		return *set[i].Key < *set[j].Key
+	})
+	return set
+}
+
+func tagMap(set []types.Tag) map[string]string {
+	tags := make(map[string]string, len(set))
+	for _, tag := range set {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
@@ -0,0 +1,32 @@
+package s3fs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestTagSet(t *testing.T) {
+	set := tagSet(map[string]string{
+		"team": "storage",
+		"env":  "test",
+	})
+	if len(set) != 2 {
+		t.Fatal("invalid tag set:", set)
+	}
+	if aws.ToString(set[0].Key) != "env" || aws.ToString(set[0].Value) != "test" {
+		t.Fatal("invalid tag:", aws.ToString(set[0].Key))
+	}
+	if aws.ToString(set[1].Key) != "team" || aws.ToString(set[1].Value) != "storage" {
+		t.Fatal("invalid tag:", aws.ToString(set[1].Key))
+	}
+}
+
+func TestTagMap(t *testing.T) {
+	tags := tagMap(tagSet(map[string]string{
+		"team": "storage",
+	}))
+	if len(tags) != 1 || tags["team"] != "storage" {
+		t.Fatal("invalid tags:", tags)
+	}
+}
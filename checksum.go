@@ -0,0 +1,197 @@
+package s3fs
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrNoChecksum       = errors.New("object has no checksum")
+)
+
+// checksumReader hashes a body while it is read and compares the result
+// with the checksum S3 stored for the object once the body is exhausted.
+// Multipart objects carry a composite checksum, the checksum of the
+// checksums of every part, so parts lists the size of each part for them.
+type checksumReader struct {
+	body      io.ReadCloser
+	algorithm types.ChecksumAlgorithm
+	want      string
+	parts     []int64
+	hash      hash.Hash
+	sums      []byte
+	remaining int64
+}
+
+// Verify downloads key and checks it against the checksum stored with it.
+// It returns ErrNoChecksum when the object was uploaded without one.
+func (s3fs *S3FS) Verify(key string) error {
+	output, err := s3fs.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:       aws.String(s3fs.config.Bucket),
+		Key:          aws.String(s3fs.getKey(key)),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return err
+	}
+	defer output.Body.Close()
+
+	if algorithm, _ := responseChecksum(output); algorithm == "" {
+		return ErrNoChecksum
+	}
+	body, err := s3fs.verifyingBody(key, output)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, body)
+	return err
+}
+
+// verifyingBody wraps the body of output so that reading it to the end
+// fails with ErrChecksumMismatch if it does not match the stored checksum.
+func (s3fs *S3FS) verifyingBody(key string, output *s3.GetObjectOutput) (io.ReadCloser, error) {
+	algorithm, want := responseChecksum(output)
+	if algorithm == "" {
+		return output.Body, nil
+	}
+	var parts []int64
+	if strings.Contains(want, "-") {
+		var err error
+		if parts, err = s3fs.partSizes(key, aws.ToString(output.VersionId)); err != nil {
+			return nil, err
+		}
+	}
+	return newChecksumReader(output.Body, algorithm, want, parts), nil
+}
+
+func (s3fs *S3FS) partSizes(key string, versionID string) ([]int64, error) {
+	sizes := []int64{}
+	var partNumberMarker *string
+	for {
+		output, err := s3fs.s3.GetObjectAttributes(ctx, &s3.GetObjectAttributesInput{
+			Bucket:           aws.String(s3fs.config.Bucket),
+			Key:              aws.String(s3fs.getKey(key)),
+			VersionId:        optString(versionID),
+			ObjectAttributes: []types.ObjectAttributes{types.ObjectAttributesObjectParts},
+			PartNumberMarker: partNumberMarker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if output.ObjectParts == nil {
+			return sizes, nil
+		}
+		for _, part := range output.ObjectParts.Parts {
+			sizes = append(sizes, aws.ToInt64(part.Size))
+		}
+		if !aws.ToBool(output.ObjectParts.IsTruncated) {
+			return sizes, nil
+		}
+		partNumberMarker = output.ObjectParts.NextPartNumberMarker
+	}
+}
+
+// responseChecksum returns the algorithm and value of the checksum S3
+// returned with an object, or an empty algorithm when there is none this
+// package can verify.
+func responseChecksum(output *s3.GetObjectOutput) (types.ChecksumAlgorithm, string) {
+	for _, c := range []struct {
+		algorithm types.ChecksumAlgorithm
+		value     *string
+	}{
+		{types.ChecksumAlgorithmCrc32, output.ChecksumCRC32},
+		{types.ChecksumAlgorithmCrc32c, output.ChecksumCRC32C},
+		{types.ChecksumAlgorithmSha1, output.ChecksumSHA1},
+		{types.ChecksumAlgorithmSha256, output.ChecksumSHA256},
+	} {
+		if aws.ToString(c.value) != "" {
+			return c.algorithm, *c.value
+		}
+	}
+	return "", ""
+}
+
+func newChecksumHash(algorithm types.ChecksumAlgorithm) hash.Hash {
+	switch algorithm {
+	case types.ChecksumAlgorithmCrc32:
+		return crc32.NewIEEE()
+	case types.ChecksumAlgorithmCrc32c:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case types.ChecksumAlgorithmSha1:
+		return sha1.New()
+	case types.ChecksumAlgorithmSha256:
+		return sha256.New()
+	}
+	return nil
+}
+
+func newChecksumReader(body io.ReadCloser, algorithm types.ChecksumAlgorithm, want string, parts []int64) *checksumReader {
+	r := &checksumReader{
+		body:      body,
+		algorithm: algorithm,
+		want:      want,
+		parts:     parts,
+		hash:      newChecksumHash(algorithm),
+		remaining: -1,
+	}
+	if len(parts) > 0 {
+		r.remaining = parts[0]
+		r.parts = parts[1:]
+	}
+	return r
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.write(p[:n])
+	if err == io.EOF && r.sum() != r.want {
+		return n, ErrChecksumMismatch
+	}
+	return n, err
+}
+
+func (r *checksumReader) Close() error {
+	return r.body.Close()
+}
+
+// write hashes b, finishing the checksum of each part as its last byte is
+// written.
+func (r *checksumReader) write(b []byte) {
+	for r.remaining >= 0 && int64(len(b)) >= r.remaining {
+		r.hash.Write(b[:r.remaining])
+		b = b[r.remaining:]
+		r.sums = r.hash.Sum(r.sums)
+		r.hash = newChecksumHash(r.algorithm)
+		r.remaining = -1
+		if len(r.parts) > 0 {
+			r.remaining = r.parts[0]
+			r.parts = r.parts[1:]
+		}
+	}
+	r.hash.Write(b)
+	if r.remaining > 0 {
+		r.remaining -= int64(len(b))
+	}
+}
+
+func (r *checksumReader) sum() string {
+	if r.sums == nil {
+		return base64.StdEncoding.EncodeToString(r.hash.Sum(nil))
+	}
+	size := r.hash.Size()
+	composite := newChecksumHash(r.algorithm)
+	composite.Write(r.sums)
+	return base64.StdEncoding.EncodeToString(composite.Sum(nil)) + "-" + strconv.Itoa(len(r.sums)/size)
+}
@@ -0,0 +1,94 @@
+package s3fs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestChecksumReader(t *testing.T) {
+	body := []byte("this is test string")
+	sum := sha256.Sum256(body)
+	want := base64.StdEncoding.EncodeToString(sum[:])
+
+	t.Run("full object", func(st *testing.T) {
+		r := newChecksumReader(ioutil.NopCloser(bytes.NewReader(body)), types.ChecksumAlgorithmSha256, want, nil)
+		if _, err := io.ReadAll(r); err != nil {
+			st.Fatal("checksum error:", err)
+		}
+	})
+	t.Run("mismatch", func(st *testing.T) {
+		r := newChecksumReader(ioutil.NopCloser(bytes.NewReader([]byte("corrupted"))), types.ChecksumAlgorithmSha256, want, nil)
+		if _, err := io.ReadAll(r); !errors.Is(err, ErrChecksumMismatch) {
+			st.Fatal("mismatch should be detected:", err)
+		}
+	})
+	t.Run("composite", func(st *testing.T) {
+		first := sha256.Sum256(body[:8])
+		second := sha256.Sum256(body[8:])
+		composite := sha256.Sum256(append(first[:], second[:]...))
+		want := base64.StdEncoding.EncodeToString(composite[:]) + "-2"
+
+		// Read a byte at a time so that part boundaries fall inside reads.
+		reader := iotest.OneByteReader(bytes.NewReader(body))
+		r := newChecksumReader(ioutil.NopCloser(reader), types.ChecksumAlgorithmSha256, want, []int64{8, int64(len(body) - 8)})
+		if _, err := io.ReadAll(r); err != nil {
+			st.Fatal("checksum error:", err)
+		}
+
+		r = newChecksumReader(ioutil.NopCloser(bytes.NewReader(body)), types.ChecksumAlgorithmSha256, want, []int64{8, int64(len(body) - 8)})
+		if _, err := io.ReadAll(r); err != nil {
+			st.Fatal("checksum error:", err)
+		}
+	})
+	t.Run("crc32c", func(st *testing.T) {
+		h := newChecksumHash(types.ChecksumAlgorithmCrc32c)
+		h.Write(body)
+		want := base64.StdEncoding.EncodeToString(h.Sum(nil))
+		r := newChecksumReader(ioutil.NopCloser(bytes.NewReader(body)), types.ChecksumAlgorithmCrc32c, want, nil)
+		if _, err := io.ReadAll(r); err != nil {
+			st.Fatal("checksum error:", err)
+		}
+	})
+}
+
+func TestSameContent(t *testing.T) {
+	head := func(etag string, sse types.ServerSideEncryption, sha256 string) *s3.HeadObjectOutput {
+		return &s3.HeadObjectOutput{
+			ContentLength:        aws.Int64(10),
+			ETag:                 aws.String(etag),
+			ServerSideEncryption: sse,
+			ChecksumSHA256:       optString(sha256),
+		}
+	}
+	for _, test := range []struct {
+		name string
+		src  *s3.HeadObjectOutput
+		dest *s3.HeadObjectOutput
+		same bool
+	}{
+		{"same md5", head(`"a"`, "", ""), head(`"a"`, "", ""), true},
+		{"different md5", head(`"a"`, "", "x"), head(`"b"`, "", "x"), false},
+		{"multipart copy", head(`"a-2"`, "", "x"), head(`"b"`, "", "x"), true},
+		{"multipart without checksum", head(`"a-2"`, "", ""), head(`"b"`, "", ""), false},
+		{"multipart with other content", head(`"a-2"`, "", "x"), head(`"b"`, "", "y"), false},
+		{"kms copy", head(`"a"`, types.ServerSideEncryptionAwsKms, "x"), head(`"b"`, types.ServerSideEncryptionAwsKms, "x"), true},
+		{"kms without checksum", head(`"a"`, types.ServerSideEncryptionAwsKms, ""), head(`"b"`, types.ServerSideEncryptionAwsKms, ""), false},
+		{"different size", head(`"a"`, "", ""), &s3.HeadObjectOutput{ContentLength: aws.Int64(11), ETag: aws.String(`"a"`)}, false},
+	} {
+		t.Run(test.name, func(st *testing.T) {
+			if sameContent(test.src, test.dest) != test.same {
+				st.Fatal("sameContent should be", test.same)
+			}
+		})
+	}
+}
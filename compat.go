@@ -0,0 +1,175 @@
+package s3fs
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Provider names an S3 implementation whose quirks New should account for.
+type Provider string
+
+const (
+	ProviderAWS    Provider = "aws"
+	ProviderMinIO  Provider = "minio"
+	ProviderR2     Provider = "r2"
+	ProviderWasabi Provider = "wasabi"
+	ProviderCeph   Provider = "ceph"
+	ProviderGCS    Provider = "gcs"
+	ProviderB2     Provider = "b2"
+)
+
+// compatProfile holds the client settings an S3 implementation needs and
+// the APIs it lacks.
+type compatProfile struct {
+	PathStyle                  bool
+	RequestChecksumCalculation aws.RequestChecksumCalculation
+	ResponseChecksumValidation aws.ResponseChecksumValidation
+	// Region is used when Config.Region is empty. It defaults to
+	// ap-northeast-1.
+	Region string
+	// EndpointRegion derives the region from Config.Endpoint when
+	// Config.Region is empty, taking precedence over Region.
+	EndpointRegion func(endpoint string) string
+	// LocationConstraint sends the region with CreateBucket.
+	LocationConstraint bool
+	// DefaultLocation also sends the region when it was not configured.
+	// Only AWS serves the default region; other servers, such as MinIO
+	// with MINIO_REGION, may be set up for any region.
+	DefaultLocation bool
+	// MultiObjectDelete reports whether DeleteObjects is supported; without
+	// it objects are deleted one at a time.
+	MultiObjectDelete bool
+	// MultipartCopy reports whether UploadPartCopy is supported; without it
+	// objects of any size are copied with a single CopyObject call.
+	MultipartCopy bool
+}
+
+var (
+	awsProfile = compatProfile{
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenSupported,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenSupported,
+		Region:                     "ap-northeast-1",
+		LocationConstraint:         true,
+		DefaultLocation:            true,
+		MultiObjectDelete:          true,
+		MultipartCopy:              true,
+	}
+	// Older MinIO releases reject the flexible checksum trailers the SDK
+	// sends by default, so checksums are only sent when required.
+	minioProfile = compatProfile{
+		PathStyle:                  true,
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+		Region:                     "ap-northeast-1",
+		LocationConstraint:         true,
+		MultiObjectDelete:          true,
+		MultipartCopy:              true,
+	}
+	// R2 signs every request for the "auto" region and picks the bucket
+	// location itself.
+	r2Profile = compatProfile{
+		PathStyle:                  true,
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+		Region:                     "auto",
+		MultiObjectDelete:          true,
+		MultipartCopy:              true,
+	}
+	wasabiProfile = compatProfile{
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+		Region:                     "us-east-1",
+		LocationConstraint:         true,
+		MultiObjectDelete:          true,
+		MultipartCopy:              true,
+	}
+	// RGW only accepts its own zonegroup names as location constraints, so
+	// buckets are created in the default zonegroup.
+	cephProfile = compatProfile{
+		PathStyle:                  true,
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+		Region:                     "us-east-1",
+		MultiObjectDelete:          true,
+		MultipartCopy:              true,
+	}
+	// The GCS XML API has neither DeleteObjects nor UploadPartCopy, but
+	// copies objects of any size in a single request.
+	gcsProfile = compatProfile{
+		PathStyle:                  true,
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+		Region:                     "auto",
+	}
+	// B2 derives the bucket region from the endpoint, so the region is
+	// taken from an endpoint such as https://s3.us-west-004.backblazeb2.com.
+	// Other endpoints need Config.Region.
+	b2Profile = compatProfile{
+		EndpointRegion:             b2Region,
+		PathStyle:                  true,
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+		MultiObjectDelete:          true,
+		MultipartCopy:              true,
+	}
+)
+
+// profile returns the compatibility profile for Config.Provider. Without a
+// provider, EnableMinioCompat selects MinIO and anything else AWS.
+func (config *Config) profile() compatProfile {
+	switch config.Provider {
+	case ProviderMinIO:
+		return minioProfile
+	case ProviderR2:
+		return r2Profile
+	case ProviderWasabi:
+		return wasabiProfile
+	case ProviderCeph:
+		return cephProfile
+	case ProviderGCS:
+		return gcsProfile
+	case ProviderB2:
+		return b2Profile
+	case ProviderAWS:
+		return awsProfile
+	}
+	if config.EnableMinioCompat {
+		return minioProfile
+	}
+	return awsProfile
+}
+
+func b2Region(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	region, ok := strings.CutPrefix(u.Hostname(), "s3.")
+	if !ok {
+		return ""
+	}
+	region, ok = strings.CutSuffix(region, ".backblazeb2.com")
+	if !ok || strings.Contains(region, ".") {
+		return ""
+	}
+	return region
+}
+
+// apply returns an option function configuring an S3 client for the
+// profile, with explicit Config settings taking precedence.
+func (profile compatProfile) apply(config *Config) func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.UsePathStyle = profile.PathStyle
+		o.RequestChecksumCalculation = profile.RequestChecksumCalculation
+		o.ResponseChecksumValidation = profile.ResponseChecksumValidation
+		if config.RequestChecksumCalculation != aws.RequestChecksumCalculationUnset {
+			o.RequestChecksumCalculation = config.RequestChecksumCalculation
+		}
+		if config.ResponseChecksumValidation != aws.ResponseChecksumValidationUnset {
+			o.ResponseChecksumValidation = config.ResponseChecksumValidation
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package s3fs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestCompatProfile(t *testing.T) {
+	t.Run("aws defaults", func(st *testing.T) {
+		config := &Config{}
+		o := s3.Options{}
+		config.profile().apply(config)(&o)
+		if o.UsePathStyle {
+			st.Fatal("unexpected path style")
+		}
+		if o.RequestChecksumCalculation != aws.RequestChecksumCalculationWhenSupported {
+			st.Fatal("unexpected request checksum calculation:", o.RequestChecksumCalculation)
+		}
+	})
+	t.Run("minio defaults", func(st *testing.T) {
+		config := &Config{EnableMinioCompat: true}
+		o := s3.Options{}
+		config.profile().apply(config)(&o)
+		if !o.UsePathStyle {
+			st.Fatal("expected path style")
+		}
+		if o.RequestChecksumCalculation != aws.RequestChecksumCalculationWhenRequired {
+			st.Fatal("unexpected request checksum calculation:", o.RequestChecksumCalculation)
+		}
+		if o.ResponseChecksumValidation != aws.ResponseChecksumValidationWhenRequired {
+			st.Fatal("unexpected response checksum validation:", o.ResponseChecksumValidation)
+		}
+	})
+	t.Run("config overrides profile", func(st *testing.T) {
+		config := &Config{
+			EnableMinioCompat:          true,
+			RequestChecksumCalculation: aws.RequestChecksumCalculationWhenSupported,
+		}
+		o := s3.Options{}
+		config.profile().apply(config)(&o)
+		if o.RequestChecksumCalculation != aws.RequestChecksumCalculationWhenSupported {
+			st.Fatal("unexpected request checksum calculation:", o.RequestChecksumCalculation)
+		}
+		if o.ResponseChecksumValidation != aws.ResponseChecksumValidationWhenRequired {
+			st.Fatal("unexpected response checksum validation:", o.ResponseChecksumValidation)
+		}
+	})
+}
+
+func TestProviderProfile(t *testing.T) {
+	t.Run("provider overrides minio compat", func(st *testing.T) {
+		config := &Config{EnableMinioCompat: true, Provider: ProviderGCS}
+		if config.profile().MultiObjectDelete {
+			st.Fatal("gcs does not support multi-object delete")
+		}
+	})
+	t.Run("region defaults to provider region", func(st *testing.T) {
+		fs := New(&Config{Provider: ProviderR2, Endpoint: "http://localhost:9000"})
+		if fs.config.Region != "auto" {
+			st.Fatal("unexpected region:", fs.config.Region)
+		}
+	})
+	t.Run("explicit region is kept", func(st *testing.T) {
+		fs := New(&Config{Provider: ProviderR2, Region: "us-east-1"})
+		if fs.config.Region != "us-east-1" {
+			st.Fatal("unexpected region:", fs.config.Region)
+		}
+	})
+	t.Run("b2 region from endpoint", func(st *testing.T) {
+		fs := New(&Config{Provider: ProviderB2, Endpoint: "https://s3.us-west-004.backblazeb2.com"})
+		if fs.config.Region != "us-west-004" {
+			st.Fatal("unexpected region:", fs.config.Region)
+		}
+		if region := b2Region("http://localhost:9000"); region != "" {
+			st.Fatal("unexpected region:", region)
+		}
+	})
+	t.Run("location of a defaulted region", func(st *testing.T) {
+		if New(&Config{EnableMinioCompat: true}).profile.LocationConstraint {
+			st.Fatal("minio should not be sent a defaulted region")
+		}
+		if !New(&Config{EnableMinioCompat: true, Region: "eu-west-1"}).profile.LocationConstraint {
+			st.Fatal("minio should be sent an explicit region")
+		}
+		if !New(&Config{}).profile.LocationConstraint {
+			st.Fatal("aws should be sent the default region")
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package s3fs
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// PutIfAbsent creates key only if it does not exist yet and returns the ETag
+// of the new object. It returns ErrPreconditionFailed when key exists.
+func (s3fs *S3FS) PutIfAbsent(key string, body io.ReadCloser) (string, error) {
+	return s3fs.putConditional(key, body, &s3.PutObjectInput{
+		IfNoneMatch: aws.String("*"),
+	})
+}
+
+// PutIfMatch replaces key only if its current ETag is etag and returns the
+// ETag of the new object. It returns ErrPreconditionFailed when key was
+// changed or deleted since etag was read.
+func (s3fs *S3FS) PutIfMatch(key string, body io.ReadCloser, etag string) (string, error) {
+	return s3fs.putConditional(key, body, &s3.PutObjectInput{
+		IfMatch: aws.String(etag),
+	})
+}
+
+// GetWithETag returns the body of key along with its ETag, for use with
+// PutIfMatch in read-modify-write loops.
+func (s3fs *S3FS) GetWithETag(key string) (*io.ReadCloser, string, error) {
+	body, etag, err := s3fs.get(key)
+	if err != nil {
+		return nil, "", err
+	}
+	return &body, etag, nil
+}
+
+// putConditional uploads body with a single PutObject call, since the
+// conditions cannot be applied to a multipart upload part by part. Bodies
+// that cannot seek are buffered so the request can be signed and retried.
+func (s3fs *S3FS) putConditional(key string, body io.ReadCloser, input *s3.PutObjectInput) (string, error) {
+	var reader io.Reader = body
+	if _, ok := body.(io.ReadSeeker); !ok {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		reader = bytes.NewReader(data)
+	}
+	input.Bucket = aws.String(s3fs.config.Bucket)
+	input.Key = aws.String(s3fs.getKey(key))
+	input.Body = reader
+	input.ChecksumAlgorithm = s3fs.config.ChecksumAlgorithm
+
+	output, err := s3fs.s3.PutObject(ctx, input)
+	if isPreconditionFailed(err) {
+		return "", ErrPreconditionFailed
+	}
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(output.ETag), nil
+}
@@ -0,0 +1,96 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// leaderTTL is how long a leader keeps its lease without renewing it. The
+// lease is renewed, and followers campaign, every third of it.
+const leaderTTL = 15 * time.Second
+
+// Leader is held by the winner of an election until it resigns, its context
+// is cancelled or the lease is lost.
+type Leader struct {
+	lock   *Lock
+	lost   chan struct{}
+	stop   chan struct{}
+	once   sync.Once
+	resign error
+}
+
+// Elect campaigns for leadership of name as id and blocks until it is
+// elected or ctx is done. Leadership is backed by a lock lease, so a leader
+// that stops renewing it is replaced once leaderTTL has passed. A candidate
+// that restarts with the id of the current leader is elected immediately.
+func (s3fs *S3FS) Elect(ctx context.Context, name string, id string) (*Leader, error) {
+	for {
+		lock, err := s3fs.lock(name, id, leaderTTL)
+		if err == nil {
+			leader := &Leader{
+				lock: lock,
+				lost: make(chan struct{}),
+				stop: make(chan struct{}),
+			}
+			go leader.run(ctx)
+			return leader, nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(leaderTTL / 3):
+		}
+	}
+}
+
+// Lost is closed when leadership ends, whether the lease was lost or the
+// leader resigned.
+func (l *Leader) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Lease returns the lease backing leadership.
+func (l *Leader) Lease() Lease {
+	return l.lock.Lease()
+}
+
+// Resign gives up leadership and releases the lease so that another
+// candidate can be elected without waiting for it to expire.
+func (l *Leader) Resign() error {
+	l.once.Do(func() { close(l.stop) })
+	<-l.lost
+	return l.resign
+}
+
+// run renews the lease until the leader resigns, ctx is done or the lease
+// is lost. Renewal errors other than a lost lease are retried, but once the
+// lease would expire before the next attempt, leadership is given up so that
+// Lost is closed before another candidate can take the lease over.
+func (l *Leader) run(ctx context.Context) {
+	defer close(l.lost)
+	ticker := time.NewTicker(leaderTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			l.resign = l.lock.Unlock()
+			return
+		case <-ctx.Done():
+			l.resign = l.lock.Unlock()
+			return
+		case <-ticker.C:
+			err := l.lock.Renew()
+			if errors.Is(err, ErrLockLost) {
+				return
+			}
+			if err != nil && time.Until(l.lock.Lease().Deadline) < leaderTTL/3 {
+				return
+			}
+		}
+	}
+}
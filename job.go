@@ -0,0 +1,345 @@
+package s3fs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// jobCheckpointEvery is how many completed keys a job processes between
+// checkpoints within a page.
+const jobCheckpointEvery = 100
+
+// maxJobErrors bounds the errors kept in a job record.
+const maxJobErrors = 100
+
+type (
+	JobKind  string
+	JobState string
+)
+
+const (
+	JobCopy   JobKind = "copy"
+	JobDelete JobKind = "delete"
+	JobMove   JobKind = "move"
+)
+
+const (
+	JobRunning JobState = "running"
+	JobPaused  JobState = "paused"
+	JobFailed  JobState = "failed"
+	JobDone    JobState = "done"
+)
+
+type (
+	// Job is the checkpointed state of a bulk copy, delete or move. Keys are
+	// processed a listing page at a time; ContinuationToken is the token of
+	// the page in progress and Completed the keys of it already processed.
+	Job struct {
+		ID                string      `json:"id"`
+		Kind              JobKind     `json:"kind"`
+		Src               string      `json:"src"`
+		Dest              string      `json:"dest,omitempty"`
+		Options           CopyOptions `json:"options"`
+		State             JobState    `json:"state"`
+		ContinuationToken string      `json:"continuationToken,omitempty"`
+		Completed         []string    `json:"completed,omitempty"`
+		Processed         int64       `json:"processed"`
+		Failed            int64       `json:"failed"`
+		Errors            []string    `json:"errors,omitempty"`
+		Created           time.Time   `json:"created"`
+		Updated           time.Time   `json:"updated"`
+	}
+	// jobRun is a job running in this process.
+	jobRun struct {
+		stop chan struct{}
+		done chan struct{}
+		once sync.Once
+		err  error
+	}
+)
+
+var (
+	ErrNoJob      = errors.New("job not found")
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// runningJobs maps the ID of every job running in this process to its
+// *jobRun.
+var runningJobs sync.Map
+
+// StartJob starts a bulk copy, delete or move of everything under src in
+// the background and returns the job ID. Copies and moves place src inside
+// dest like Copy and Move do; deletes ignore dest. Progress is checkpointed
+// to Config.JobStore, by default the bucket itself.
+func (s3fs *S3FS) StartJob(kind JobKind, src string, dest string, opts CopyOptions) (string, error) {
+	now := time.Now().UTC()
+	job := &Job{
+		ID:      newID(),
+		Kind:    kind,
+		Src:     src,
+		Dest:    dest,
+		Options: opts,
+		State:   JobRunning,
+		Created: now,
+		Updated: now,
+	}
+	if err := s3fs.jobStore().Save(job); err != nil {
+		return "", err
+	}
+	s3fs.runJob(job)
+	return job.ID, nil
+}
+
+// ResumeJob continues a paused, failed or interrupted job in the
+// background from its last checkpoint. Keys already processed are skipped.
+func (s3fs *S3FS) ResumeJob(id string) error {
+	if _, ok := runningJobs.Load(id); ok {
+		return ErrJobRunning
+	}
+	job, err := s3fs.jobStore().Load(id)
+	if err != nil {
+		return err
+	}
+	if job.State == JobDone {
+		return nil
+	}
+	job.State = JobRunning
+	job.Errors = nil
+	s3fs.runJob(job)
+	return nil
+}
+
+// PauseJob stops a job running in this process at the next key and waits
+// for it to checkpoint.
+func (s3fs *S3FS) PauseJob(id string) error {
+	v, ok := runningJobs.Load(id)
+	if !ok {
+		return ErrNoJob
+	}
+	run := v.(*jobRun)
+	run.once.Do(func() { close(run.stop) })
+	<-run.done
+	return nil
+}
+
+// WaitJob blocks until a job running in this process stops, and returns
+// the error that stopped it, if any.
+func (s3fs *S3FS) WaitJob(id string) error {
+	v, ok := runningJobs.Load(id)
+	if !ok {
+		return nil
+	}
+	run := v.(*jobRun)
+	<-run.done
+	return run.err
+}
+
+// JobStatus returns the last checkpoint of a job.
+func (s3fs *S3FS) JobStatus(id string) (*Job, error) {
+	return s3fs.jobStore().Load(id)
+}
+
+func (s3fs *S3FS) jobStore() JobStore {
+	if s3fs.config.JobStore != nil {
+		return s3fs.config.JobStore
+	}
+	return NewS3JobStore(s3fs)
+}
+
+func (s3fs *S3FS) runJob(job *Job) {
+	run := &jobRun{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	runningJobs.Store(job.ID, run)
+	go func() {
+		defer runningJobs.Delete(job.ID)
+		defer close(run.done)
+		run.err = s3fs.processJob(job, run.stop)
+	}()
+}
+
+// processJob works through the pages of job until it is done, fails or is
+// stopped, saving a checkpoint whenever it stops and after every page.
+func (s3fs *S3FS) processJob(job *Job, stop chan struct{}) error {
+	store := s3fs.jobStore()
+	for {
+		list, err := s3fs.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(s3fs.config.Bucket),
+			Prefix:            aws.String(s3fs.getKey(job.Src)),
+			ContinuationToken: optString(job.ContinuationToken),
+		})
+		if err != nil {
+			return s3fs.failJob(job, err)
+		}
+
+		completed := map[string]bool{}
+		for _, key := range job.Completed {
+			completed[key] = true
+		}
+		pending := []types.Object{}
+		for _, obj := range list.Contents {
+			if !completed[*obj.Key] && !s3fs.isReserved(*obj.Key) {
+				pending = append(pending, obj)
+			}
+		}
+
+		stopped, err := s3fs.processPage(job, pending, stop)
+		if err != nil {
+			return s3fs.failJob(job, err)
+		}
+		if stopped {
+			job.State = JobPaused
+			return s3fs.saveJob(store, job)
+		}
+
+		job.Completed = nil
+		if !aws.ToBool(list.IsTruncated) {
+			job.ContinuationToken = ""
+			job.State = JobDone
+			return s3fs.saveJob(store, job)
+		}
+		job.ContinuationToken = aws.ToString(list.NextContinuationToken)
+		if err := s3fs.saveJob(store, job); err != nil {
+			return err
+		}
+	}
+}
+
+// processPage processes objects of the current page. It reports whether
+// it was stopped before processing all of them.
+func (s3fs *S3FS) processPage(job *Job, objects []types.Object, stop chan struct{}) (bool, error) {
+	if job.Kind == JobDelete {
+		if isStopped(stop) {
+			return true, nil
+		}
+		ids := make([]types.ObjectIdentifier, 0, len(objects))
+		for _, obj := range objects {
+			ids = append(ids, types.ObjectIdentifier{Key: obj.Key})
+		}
+		if err := s3fs.deleteObjects(s3fs.config.Bucket, ids); err != nil {
+			job.Failed += int64(len(objects))
+			return false, err
+		}
+		job.Processed += int64(len(objects))
+		return false, nil
+	}
+
+	store := s3fs.jobStore()
+	g := &group{}
+	sem := make(chan struct{}, requestConcurrency)
+	var mu sync.Mutex
+	var checkpointErr error
+	stopped := false
+	for _, obj := range objects {
+		// A pause takes precedence over a free slot.
+		if isStopped(stop) {
+			stopped = true
+			break
+		}
+		select {
+		case <-stop:
+			stopped = true
+		case sem <- struct{}{}:
+		}
+		if stopped {
+			break
+		}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			err := s3fs.processObject(job, obj)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				job.Failed++
+				if len(job.Errors) < maxJobErrors {
+					job.Errors = append(job.Errors, s3fs.pathOf(*obj.Key)+": "+err.Error())
+				}
+				return err
+			}
+			job.Processed++
+			job.Completed = append(job.Completed, *obj.Key)
+			if len(job.Completed)%jobCheckpointEvery == 0 {
+				if err := s3fs.saveJob(store, job); err != nil {
+					checkpointErr = fmt.Errorf("checkpoint: %w", err)
+					return checkpointErr
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		if checkpointErr != nil {
+			return false, checkpointErr
+		}
+		return false, errors.New("some files failed")
+	}
+	return stopped, nil
+}
+
+// isStopped reports whether stop is closed, without blocking.
+func isStopped(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s3fs *S3FS) processObject(job *Job, obj types.Object) error {
+	_, target := s3fs.copyTarget(job.Src, job.Dest, *obj.Key)
+	// Jobs do not list destinations up front, so each one is treated as
+	// existing and only skipped when its ETag matches.
+	entry := &MoveEntry{
+		Src:     s3fs.pathOf(*obj.Key),
+		Dest:    target,
+		ETag:    aws.ToString(obj.ETag),
+		Size:    aws.ToInt64(obj.Size),
+		Existed: true,
+	}
+	if job.Kind == JobMove {
+		if err := s3fs.moveEntry(entry, job.Options); err != nil {
+			return err
+		}
+		if entry.Skipped {
+			return nil
+		}
+		return s3fs.deleteSource(*entry)
+	}
+
+	var err error
+	if strings.HasSuffix(entry.Src, "/") {
+		err = s3fs.MkDir(entry.Dest)
+	} else {
+		err = s3fs.singleCopy(entry.Src, entry.Dest, job.Options)
+	}
+	if errors.Is(err, ErrPreconditionFailed) {
+		return nil
+	}
+	return err
+}
+
+func (s3fs *S3FS) failJob(job *Job, err error) error {
+	job.State = JobFailed
+	if len(job.Errors) < maxJobErrors {
+		job.Errors = append(job.Errors, err.Error())
+	}
+	if err := s3fs.saveJob(s3fs.jobStore(), job); err != nil {
+		return err
+	}
+	return err
+}
+
+func (s3fs *S3FS) saveJob(store JobStore, job *Job) error {
+	job.Updated = time.Now().UTC()
+	return store.Save(job)
+}
@@ -0,0 +1,98 @@
+package s3fs
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// memoryJobStore keeps every checkpoint saved to it. While err is set,
+// Save fails with it instead.
+type memoryJobStore struct {
+	mu          sync.Mutex
+	checkpoints []Job
+	err         error
+}
+
+func (store *memoryJobStore) Save(job *Job) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.err != nil {
+		return store.err
+	}
+	checkpoint := *job
+	checkpoint.Completed = append([]string(nil), job.Completed...)
+	store.checkpoints = append(store.checkpoints, checkpoint)
+	return nil
+}
+
+func (store *memoryJobStore) Load(id string) (*Job, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for i := len(store.checkpoints) - 1; i >= 0; i-- {
+		if store.checkpoints[i].ID == id {
+			job := store.checkpoints[i]
+			return &job, nil
+		}
+	}
+	return nil, ErrNoJob
+}
+
+func TestFileJobStore(t *testing.T) {
+	store := NewFileJobStore(t.TempDir())
+	t.Run("round trip", func(st *testing.T) {
+		job := &Job{
+			ID:                "job",
+			Kind:              JobCopy,
+			Src:               "/src/",
+			Dest:              "/dest/",
+			State:             JobPaused,
+			ContinuationToken: "token",
+			Completed:         []string{"src/a", "src/b"},
+			Processed:         2,
+		}
+		if err := store.Save(job); err != nil {
+			st.Fatal("save error:", err)
+		}
+		loaded, err := store.Load("job")
+		if err != nil {
+			st.Fatal("load error:", err)
+		}
+		if loaded.State != JobPaused || loaded.ContinuationToken != "token" || len(loaded.Completed) != 2 || loaded.Processed != 2 {
+			st.Fatal("checkpoint error:", loaded)
+		}
+	})
+	t.Run("unknown job", func(st *testing.T) {
+		if _, err := store.Load("missing"); !errors.Is(err, ErrNoJob) {
+			st.Fatal("load error:", err)
+		}
+	})
+}
+
+func TestProcessPageStopped(t *testing.T) {
+	tenant := &S3FS{config: &Config{JobStore: &memoryJobStore{}}}
+	stop := make(chan struct{})
+	close(stop)
+	objects := []types.Object{
+		{Key: aws.String("src/a")},
+		{Key: aws.String("src/b")},
+	}
+	for _, kind := range []JobKind{JobCopy, JobDelete, JobMove} {
+		t.Run(string(kind), func(st *testing.T) {
+			job := &Job{ID: "job", Kind: kind, Src: "/src/", Dest: "/dest/", State: JobRunning}
+			stopped, err := tenant.processPage(job, objects, stop)
+			if err != nil {
+				st.Fatal("process error:", err)
+			}
+			if !stopped {
+				st.Fatal("page should be stopped")
+			}
+			if job.Processed != 0 || len(job.Completed) != 0 {
+				st.Fatal("no key should be processed:", job)
+			}
+		})
+	}
+}
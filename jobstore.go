@@ -0,0 +1,94 @@
+package s3fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// jobPrefix is the reserved directory, inside the tenant, holding the job
+// objects of NewS3JobStore.
+const jobPrefix = reservedPrefix + "jobs/"
+
+// JobStore persists job checkpoints. Load returns ErrNoJob for unknown IDs.
+type JobStore interface {
+	Save(job *Job) error
+	Load(id string) (*Job, error)
+}
+
+type (
+	fileJobStore struct {
+		dir string
+	}
+	s3JobStore struct {
+		fs *S3FS
+	}
+)
+
+// NewFileJobStore keeps job checkpoints as JSON files in dir.
+func NewFileJobStore(dir string) JobStore {
+	return &fileJobStore{dir}
+}
+
+// NewS3JobStore keeps job checkpoints as JSON objects in the bucket of fs.
+func NewS3JobStore(fs *S3FS) JobStore {
+	return &s3JobStore{fs}
+}
+
+func (store *fileJobStore) Save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(store.dir, 0o755); err != nil {
+		return err
+	}
+	// Write then rename, so a crash never leaves a truncated checkpoint.
+	path := filepath.Join(store.dir, job.ID+".json")
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (store *fileJobStore) Load(id string) (*Job, error) {
+	data, err := os.ReadFile(filepath.Join(store.dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoJob
+	}
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (store *s3JobStore) Save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return store.fs.Put(jobPrefix+job.ID+".json", io.NopCloser(bytes.NewReader(data)), "application/json")
+}
+
+func (store *s3JobStore) Load(id string) (*Job, error) {
+	body, err := store.fs.Get(jobPrefix + id + ".json")
+	if errorCode(err) == "NoSuchKey" {
+		return nil, ErrNoJob
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer (*body).Close()
+	job := &Job{}
+	if err := json.NewDecoder(*body).Decode(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,270 @@
+package s3fs
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type (
+	// LifecycleRule is a bucket lifecycle rule whose Prefix is relative to
+	// the NameSpace/Domain root of the S3FS it is applied through. Its ID
+	// may not contain "/".
+	LifecycleRule struct {
+		ID                           string
+		Prefix                       string
+		Enabled                      bool
+		ExpirationDays               int32
+		NoncurrentExpirationDays     int32
+		NewerNoncurrentVersions      int32
+		AbortIncompleteMultipartDays int32
+		Transitions                  []LifecycleTransition
+	}
+	LifecycleTransition struct {
+		Days         int32
+		StorageClass types.TransitionStorageClass
+	}
+)
+
+var ErrInvalidRuleID = errors.New("lifecycle rule ID must not contain /")
+
+// NewLifecycleRule returns an enabled rule without any action. Chain the
+// builder methods to add actions.
+func NewLifecycleRule(id string, prefix string) *LifecycleRule {
+	return &LifecycleRule{
+		ID:      id,
+		Prefix:  prefix,
+		Enabled: true,
+	}
+}
+
+// Expire deletes current versions the given number of days after creation.
+func (rule *LifecycleRule) Expire(days int32) *LifecycleRule {
+	rule.ExpirationDays = days
+	return rule
+}
+
+// ExpireNoncurrent deletes noncurrent versions the given number of days after
+// they became noncurrent, keeping the newest keep versions regardless.
+func (rule *LifecycleRule) ExpireNoncurrent(days int32, keep int32) *LifecycleRule {
+	rule.NoncurrentExpirationDays = days
+	rule.NewerNoncurrentVersions = keep
+	return rule
+}
+
+func (rule *LifecycleRule) AbortIncompleteMultipart(days int32) *LifecycleRule {
+	rule.AbortIncompleteMultipartDays = days
+	return rule
+}
+
+func (rule *LifecycleRule) Transition(days int32, storageClass types.TransitionStorageClass) *LifecycleRule {
+	rule.Transitions = append(rule.Transitions, LifecycleTransition{
+		Days:         days,
+		StorageClass: storageClass,
+	})
+	return rule
+}
+
+func (rule *LifecycleRule) Disable() *LifecycleRule {
+	rule.Enabled = false
+	return rule
+}
+
+// GetLifecycle returns the lifecycle rules that apply under the
+// NameSpace/Domain root. Rules of other tenants are not returned.
+func (s3fs *S3FS) GetLifecycle() ([]LifecycleRule, error) {
+	all, err := s3fs.bucketLifecycle()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]LifecycleRule, 0)
+	for _, rule := range all {
+		if s3fs.ownsRule(rule) {
+			rules = append(rules, s3fs.fromLifecycleRule(rule))
+		}
+	}
+	return rules, nil
+}
+
+// PutLifecycle replaces the lifecycle rules under the NameSpace/Domain root.
+// Rules belonging to other tenants of the bucket, including those of
+// Domains nested under this NameSpace, are preserved unchanged. S3 only
+// accepts the whole bucket configuration at once, so concurrent calls
+// through different tenants can overwrite each other's changes; callers
+// sharing a bucket should serialise them, for example with Lock. A rule
+// replacing one with the same ID keeps the parts of it LifecycleRule does
+// not model: noncurrent version transitions and tag or size filters.
+func (s3fs *S3FS) PutLifecycle(rules ...*LifecycleRule) error {
+	for _, rule := range rules {
+		if strings.Contains(rule.ID, "/") {
+			return ErrInvalidRuleID
+		}
+	}
+	all, err := s3fs.bucketLifecycle()
+	if err != nil {
+		return err
+	}
+	merged := make([]types.LifecycleRule, 0, len(all)+len(rules))
+	owned := map[string]types.LifecycleRule{}
+	for _, rule := range all {
+		if s3fs.ownsRule(rule) {
+			owned[aws.ToString(rule.ID)] = rule
+		} else {
+			merged = append(merged, rule)
+		}
+	}
+	for _, rule := range rules {
+		converted := s3fs.toLifecycleRule(rule)
+		if current, ok := owned[aws.ToString(converted.ID)]; ok {
+			keepUnmodeled(&converted, current)
+		}
+		merged = append(merged, converted)
+	}
+	return s3fs.putBucketLifecycle(merged)
+}
+
+// keepUnmodeled copies the parts of current that LifecycleRule cannot
+// express onto rule, which replaces it.
+func keepUnmodeled(rule *types.LifecycleRule, current types.LifecycleRule) {
+	rule.NoncurrentVersionTransitions = current.NoncurrentVersionTransitions
+	filter := current.Filter
+	if filter == nil || (filter.And == nil && filter.Tag == nil &&
+		filter.ObjectSizeGreaterThan == nil && filter.ObjectSizeLessThan == nil) {
+		return
+	}
+	// A prefix can only be combined with other conditions through And.
+	and := &types.LifecycleRuleAndOperator{
+		ObjectSizeGreaterThan: filter.ObjectSizeGreaterThan,
+		ObjectSizeLessThan:    filter.ObjectSizeLessThan,
+	}
+	if filter.And != nil {
+		and.ObjectSizeGreaterThan = filter.And.ObjectSizeGreaterThan
+		and.ObjectSizeLessThan = filter.And.ObjectSizeLessThan
+		and.Tags = append(and.Tags, filter.And.Tags...)
+	}
+	if filter.Tag != nil {
+		and.Tags = append(and.Tags, *filter.Tag)
+	}
+	and.Prefix = rule.Filter.Prefix
+	rule.Filter = &types.LifecycleRuleFilter{And: and}
+}
+
+// DeleteLifecycle removes the lifecycle rules under the NameSpace/Domain
+// root, leaving the rules of other tenants in place.
+func (s3fs *S3FS) DeleteLifecycle() error {
+	return s3fs.PutLifecycle()
+}
+
+func (s3fs *S3FS) bucketLifecycle() ([]types.LifecycleRule, error) {
+	output, err := s3fs.s3.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	if errorCode(err) == "NoSuchLifecycleConfiguration" {
+		return []types.LifecycleRule{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return output.Rules, nil
+}
+
+func (s3fs *S3FS) putBucketLifecycle(rules []types.LifecycleRule) error {
+	if len(rules) == 0 {
+		_, err := s3fs.s3.DeleteBucketLifecycle(ctx, &s3.DeleteBucketLifecycleInput{
+			Bucket: aws.String(s3fs.config.Bucket),
+		})
+		return err
+	}
+	_, err := s3fs.s3.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{
+			Rules: rules,
+		},
+	})
+	return err
+}
+
+// ownsRule reports whether rule was written through this NameSpace/Domain
+// root: its ID is a rule ID scoped with exactly this root, not a deeper one.
+func (s3fs *S3FS) ownsRule(rule types.LifecycleRule) bool {
+	id, ok := strings.CutPrefix(aws.ToString(rule.ID), s3fs.getKey(""))
+	return ok && id != "" && !strings.Contains(id, "/")
+}
+
+// toLifecycleRule converts rule for the bucket configuration. Rule IDs must
+// be unique across the bucket, so they are scoped to the tenant like keys.
+func (s3fs *S3FS) toLifecycleRule(rule *LifecycleRule) types.LifecycleRule {
+	result := types.LifecycleRule{
+		ID:     aws.String(s3fs.getKey(rule.ID)),
+		Status: types.ExpirationStatusDisabled,
+		Filter: &types.LifecycleRuleFilter{
+			Prefix: aws.String(s3fs.getKey(rule.Prefix)),
+		},
+	}
+	if rule.Enabled {
+		result.Status = types.ExpirationStatusEnabled
+	}
+	if rule.ExpirationDays > 0 {
+		result.Expiration = &types.LifecycleExpiration{
+			Days: aws.Int32(rule.ExpirationDays),
+		}
+	}
+	if rule.NoncurrentExpirationDays > 0 {
+		result.NoncurrentVersionExpiration = &types.NoncurrentVersionExpiration{
+			NoncurrentDays: aws.Int32(rule.NoncurrentExpirationDays),
+		}
+		if rule.NewerNoncurrentVersions > 0 {
+			result.NoncurrentVersionExpiration.NewerNoncurrentVersions = aws.Int32(rule.NewerNoncurrentVersions)
+		}
+	}
+	if rule.AbortIncompleteMultipartDays > 0 {
+		result.AbortIncompleteMultipartUpload = &types.AbortIncompleteMultipartUpload{
+			DaysAfterInitiation: aws.Int32(rule.AbortIncompleteMultipartDays),
+		}
+	}
+	for _, transition := range rule.Transitions {
+		result.Transitions = append(result.Transitions, types.Transition{
+			Days:         aws.Int32(transition.Days),
+			StorageClass: transition.StorageClass,
+		})
+	}
+	return result
+}
+
+func (s3fs *S3FS) fromLifecycleRule(rule types.LifecycleRule) LifecycleRule {
+	result := LifecycleRule{
+		ID:      strings.TrimPrefix(aws.ToString(rule.ID), s3fs.getKey("")),
+		Prefix:  strings.TrimPrefix(rulePrefix(rule), s3fs.getKey("")),
+		Enabled: rule.Status == types.ExpirationStatusEnabled,
+	}
+	if rule.Expiration != nil {
+		result.ExpirationDays = aws.ToInt32(rule.Expiration.Days)
+	}
+	if rule.NoncurrentVersionExpiration != nil {
+		result.NoncurrentExpirationDays = aws.ToInt32(rule.NoncurrentVersionExpiration.NoncurrentDays)
+		result.NewerNoncurrentVersions = aws.ToInt32(rule.NoncurrentVersionExpiration.NewerNoncurrentVersions)
+	}
+	if rule.AbortIncompleteMultipartUpload != nil {
+		result.AbortIncompleteMultipartDays = aws.ToInt32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+	}
+	for _, transition := range rule.Transitions {
+		result.Transitions = append(result.Transitions, LifecycleTransition{
+			Days:         aws.ToInt32(transition.Days),
+			StorageClass: transition.StorageClass,
+		})
+	}
+	return result
+}
+
+func rulePrefix(rule types.LifecycleRule) string {
+	if rule.Filter != nil {
+		if rule.Filter.And != nil {
+			return aws.ToString(rule.Filter.And.Prefix)
+		}
+		return aws.ToString(rule.Filter.Prefix)
+	}
+	return aws.ToString(rule.Prefix)
+}
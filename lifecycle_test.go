@@ -0,0 +1,94 @@
+package s3fs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestLifecycleRule(t *testing.T) {
+	tenant := &S3FS{config: &Config{NameSpace: "app", Domain: "tenant"}}
+	rule := NewLifecycleRule("logs", "logs/").
+		Expire(30).
+		ExpireNoncurrent(7, 3).
+		AbortIncompleteMultipart(1).
+		Transition(10, types.TransitionStorageClassGlacier)
+
+	converted := tenant.toLifecycleRule(rule)
+	if prefix := aws.ToString(converted.Filter.Prefix); prefix != "app/tenant/logs/" {
+		t.Fatal("invalid prefix:", prefix)
+	}
+	if converted.Status != types.ExpirationStatusEnabled {
+		t.Fatal("invalid status:", converted.Status)
+	}
+	if !tenant.ownsRule(converted) {
+		t.Fatal("rule should belong to the tenant")
+	}
+
+	other := &S3FS{config: &Config{NameSpace: "app", Domain: "other"}}
+	if other.ownsRule(converted) {
+		t.Fatal("rule should not belong to another tenant")
+	}
+	nameSpace := &S3FS{config: &Config{NameSpace: "app"}}
+	if nameSpace.ownsRule(converted) {
+		t.Fatal("rule of a domain should not belong to its namespace")
+	}
+	if !nameSpace.ownsRule(nameSpace.toLifecycleRule(NewLifecycleRule("logs", "logs/"))) {
+		t.Fatal("rule should belong to the namespace")
+	}
+	if err := nameSpace.PutLifecycle(NewLifecycleRule("tenant/logs", "logs/")); !errors.Is(err, ErrInvalidRuleID) {
+		t.Fatal("rule id error:", err)
+	}
+	if id := aws.ToString(converted.ID); id != "app/tenant/logs" {
+		t.Fatal("invalid id:", id)
+	}
+	if id := aws.ToString(other.toLifecycleRule(NewLifecycleRule("logs", "logs/")).ID); id == aws.ToString(converted.ID) {
+		t.Fatal("rule ids of different tenants should not collide:", id)
+	}
+
+	restored := tenant.fromLifecycleRule(converted)
+	if restored.Prefix != "logs/" || restored.ID != "logs" || !restored.Enabled {
+		t.Fatal("invalid rule:", restored)
+	}
+	if restored.ExpirationDays != 30 || restored.NoncurrentExpirationDays != 7 || restored.NewerNoncurrentVersions != 3 {
+		t.Fatal("invalid expiration:", restored)
+	}
+	if restored.AbortIncompleteMultipartDays != 1 {
+		t.Fatal("invalid abort days:", restored.AbortIncompleteMultipartDays)
+	}
+	if len(restored.Transitions) != 1 || restored.Transitions[0].StorageClass != types.TransitionStorageClassGlacier {
+		t.Fatal("invalid transitions:", restored.Transitions)
+	}
+}
+
+func TestKeepUnmodeled(t *testing.T) {
+	tenant := &S3FS{config: &Config{NameSpace: "app", Domain: "tenant"}}
+	current := types.LifecycleRule{
+		ID: aws.String("app/tenant/logs"),
+		Filter: &types.LifecycleRuleFilter{
+			And: &types.LifecycleRuleAndOperator{
+				Prefix: aws.String("app/tenant/old/"),
+				Tags:   []types.Tag{{Key: aws.String("class"), Value: aws.String("log")}},
+			},
+		},
+		NoncurrentVersionTransitions: []types.NoncurrentVersionTransition{
+			{NoncurrentDays: aws.Int32(30), StorageClass: types.TransitionStorageClassGlacier},
+		},
+	}
+	rule := tenant.toLifecycleRule(NewLifecycleRule("logs", "logs/").Expire(7))
+	keepUnmodeled(&rule, current)
+	if len(rule.NoncurrentVersionTransitions) != 1 {
+		t.Fatal("noncurrent transitions should be kept:", rule.NoncurrentVersionTransitions)
+	}
+	if rule.Filter.And == nil || len(rule.Filter.And.Tags) != 1 || rule.Filter.Prefix != nil {
+		t.Fatal("tag filter should be kept:", rule.Filter)
+	}
+	if prefix := aws.ToString(rule.Filter.And.Prefix); prefix != "app/tenant/logs/" {
+		t.Fatal("prefix should be replaced:", prefix)
+	}
+	if rulePrefix(rule) != "app/tenant/logs/" || aws.ToInt32(rule.Expiration.Days) != 7 {
+		t.Fatal("invalid rule:", rule)
+	}
+}
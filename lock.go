@@ -0,0 +1,188 @@
+package s3fs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// reservedPrefix is the directory, inside the tenant, holding the
+	// objects s3fs keeps for its own bookkeeping.
+	reservedPrefix = "/.s3fs/"
+	lockPrefix     = reservedPrefix + "locks/"
+)
+
+type (
+	// Lease is the content of a lock object.
+	Lease struct {
+		Name     string    `json:"name"`
+		Owner    string    `json:"owner"`
+		Deadline time.Time `json:"deadline"`
+	}
+	// Lock is a held lock. It stays held until its deadline passes unless
+	// it is renewed.
+	Lock struct {
+		fs   *S3FS
+		ttl  time.Duration
+		mu   sync.Mutex
+		etag string
+		// lease is the lease as last written by this holder.
+		lease Lease
+	}
+)
+
+var (
+	ErrLocked   = errors.New("lock is held by another owner")
+	ErrLockLost = errors.New("lock is no longer held")
+)
+
+// Lock acquires the lock called name for ttl. It returns ErrLocked when
+// another owner holds a lease that has not expired yet; an expired lease is
+// taken over. Deadlines are compared against the local clock, so ttl should
+// be well above the clock skew between hosts.
+func (s3fs *S3FS) Lock(name string, ttl time.Duration) (*Lock, error) {
+	return s3fs.lock(name, newOwnerID(), ttl)
+}
+
+func (s3fs *S3FS) lock(name string, owner string, ttl time.Duration) (*Lock, error) {
+	key := lockPrefix + name
+	// The lock object may vanish between a failed create and the read
+	// that follows it, in which case creating it is tried again.
+	for range 3 {
+		lease := Lease{
+			Name:     name,
+			Owner:    owner,
+			Deadline: time.Now().Add(ttl),
+		}
+		etag, err := s3fs.putLease(key, lease, "")
+		if err == nil {
+			return &Lock{fs: s3fs, ttl: ttl, etag: etag, lease: lease}, nil
+		}
+		if !errors.Is(err, ErrPreconditionFailed) {
+			return nil, err
+		}
+
+		current, etag, err := s3fs.getLease(key)
+		if errorCode(err) == "NoSuchKey" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !current.available(owner, time.Now()) {
+			return nil, ErrLocked
+		}
+		lease.Deadline = time.Now().Add(ttl)
+		etag, err = s3fs.putLease(key, lease, etag)
+		if errors.Is(err, ErrPreconditionFailed) {
+			return nil, ErrLocked
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Lock{fs: s3fs, ttl: ttl, etag: etag, lease: lease}, nil
+	}
+	return nil, ErrLocked
+}
+
+// Lease returns the lease as last written by this holder.
+func (l *Lock) Lease() Lease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lease
+}
+
+// Renew extends the lease by the lock's ttl from now. It returns
+// ErrLockLost when the lock object was changed or removed by someone else,
+// typically because the lease expired and was taken over.
+func (l *Lock) Renew() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lease := l.lease
+	lease.Deadline = time.Now().Add(l.ttl)
+	etag, err := l.fs.putLease(lockPrefix+lease.Name, lease, l.etag)
+	if errors.Is(err, ErrPreconditionFailed) || errorCode(err) == "NoSuchKey" {
+		return ErrLockLost
+	}
+	if err != nil {
+		return err
+	}
+	l.etag = etag
+	l.lease = lease
+	return nil
+}
+
+// Unlock releases the lock. It returns ErrLockLost when the lock was
+// already taken over, in which case the new holder's lease is left intact.
+func (l *Lock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.fs.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(l.fs.config.Bucket),
+		Key:     aws.String(l.fs.getKey(lockPrefix + l.lease.Name)),
+		IfMatch: aws.String(l.etag),
+	})
+	if isPreconditionFailed(err) || errorCode(err) == "NoSuchKey" {
+		return ErrLockLost
+	}
+	return err
+}
+
+// available reports whether owner may take the lease at now.
+func (lease Lease) available(owner string, now time.Time) bool {
+	return lease.Owner == owner || !now.Before(lease.Deadline)
+}
+
+// putLease writes lease to key, replacing the version with etag, or only
+// creating it when etag is empty.
+func (s3fs *S3FS) putLease(key string, lease Lease, etag string) (string, error) {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return "", err
+	}
+	body := io.NopCloser(bytes.NewReader(data))
+	if etag == "" {
+		return s3fs.PutIfAbsent(key, body)
+	}
+	return s3fs.PutIfMatch(key, body, etag)
+}
+
+func (s3fs *S3FS) getLease(key string) (Lease, string, error) {
+	body, etag, err := s3fs.GetWithETag(key)
+	if err != nil {
+		return Lease{}, "", err
+	}
+	defer (*body).Close()
+	lease := Lease{}
+	if err := json.NewDecoder(*body).Decode(&lease); err != nil {
+		return Lease{}, "", err
+	}
+	return lease, etag, nil
+}
+
+// isReserved reports whether the object key lies in the reserved directory
+// at the NameSpace/Domain root. Listings, bulk operations and version
+// history leave such keys alone; a .s3fs directory anywhere else is an
+// ordinary user directory.
+func (s3fs *S3FS) isReserved(key string) bool {
+	return strings.HasPrefix(key, s3fs.getKey(reservedPrefix))
+}
+
+// newOwnerID identifies a lock holder by host name and a random suffix.
+func newOwnerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
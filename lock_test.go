@@ -0,0 +1,54 @@
+package s3fs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeaseAvailable(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lease := Lease{Name: "cron", Owner: "a", Deadline: now.Add(time.Minute)}
+	t.Run("held by another owner", func(st *testing.T) {
+		if lease.available("b", now) {
+			st.Fatal("live lease should not be available")
+		}
+	})
+	t.Run("held by the same owner", func(st *testing.T) {
+		if !lease.available("a", now) {
+			st.Fatal("owner should be able to re-acquire")
+		}
+	})
+	t.Run("expired", func(st *testing.T) {
+		if !lease.available("b", now.Add(time.Minute)) {
+			st.Fatal("expired lease should be available")
+		}
+	})
+}
+
+func TestNewOwnerID(t *testing.T) {
+	a, b := newOwnerID(), newOwnerID()
+	if a == b {
+		t.Fatal("owner ids should be unique:", a)
+	}
+	if !strings.Contains(a, "-") {
+		t.Fatal("unexpected owner id:", a)
+	}
+}
+
+func TestIsReserved(t *testing.T) {
+	tenant := &S3FS{config: &Config{NameSpace: "app", Domain: "tenant"}}
+	for key, reserved := range map[string]bool{
+		"app/tenant/.s3fs/jobs/1.json": true,
+		"app/tenant/.s3fs/":            true,
+		"app/tenant/sub/.s3fs/locks/a": false,
+		"app/tenant/.s3fs":             false,
+		"app/tenant/x.s3fs/a":          false,
+		"app/other/.s3fs/locks/a":      false,
+		"app/tenant/file":              false,
+	} {
+		if tenant.isReserved(key) != reserved {
+			t.Fatal("invalid result for", key)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package s3fs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type (
+	// MetadataUpdate describes a change to the metadata and system headers
+	// of an object. Empty header fields keep their current value.
+	MetadataUpdate struct {
+		// Metadata is merged into the current user metadata, or replaces it
+		// when ReplaceMetadata is set.
+		Metadata           map[string]string
+		ReplaceMetadata    bool
+		ContentType        string
+		CacheControl       string
+		ContentDisposition string
+		ContentEncoding    string
+		ContentLanguage    string
+	}
+	// objectHeaders are the headers of an object that a copy with
+	// MetadataDirectiveReplace would otherwise drop.
+	objectHeaders struct {
+		Metadata             map[string]string
+		ContentType          string
+		CacheControl         string
+		ContentDisposition   string
+		ContentEncoding      string
+		ContentLanguage      string
+		Expires              *time.Time
+		StorageClass         types.StorageClass
+		ServerSideEncryption types.ServerSideEncryption
+		SSEKMSKeyID          string
+		// ETag is the source version the headers were read from. The copy
+		// fails with ErrPreconditionFailed if the source changed since.
+		ETag string
+		// ReplaceTags sets Tags on the copy instead of the source tags.
+		ReplaceTags bool
+		Tags        map[string]string
+	}
+)
+
+const (
+	// maxCopySize is the largest object a single CopyObject call accepts.
+	maxCopySize  int64 = 5 * 1024 * 1024 * 1024
+	copyPartSize int64 = 512 * 1024 * 1024
+)
+
+// SetMetadata updates the user metadata and system headers of key in place
+// by copying the object onto itself. Everything not named in update,
+// including tags and storage class, is preserved. Objects larger than 5 GB
+// are rewritten with a multipart copy.
+func (s3fs *S3FS) SetMetadata(key string, update MetadataUpdate) error {
+	head, err := s3fs.s3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(key)),
+	})
+	if err != nil {
+		return err
+	}
+	return s3fs.rewriteObject(key, key, aws.ToInt64(head.ContentLength), mergeHeaders(head, update))
+}
+
+// rewriteObject copies src, which is size bytes long, to dest with the given
+// headers instead of the source ones.
+func (s3fs *S3FS) rewriteObject(src string, dest string, size int64, headers objectHeaders) error {
+	if size > maxCopySize && s3fs.profile.MultipartCopy {
+		return s3fs.multipartCopy(src, dest, size, headers)
+	}
+
+	_, err := s3fs.s3.CopyObject(ctx, s3fs.rewriteInput(src, dest, headers))
+	if isPreconditionFailed(err) {
+		return ErrPreconditionFailed
+	}
+	return err
+}
+
+// rewriteInput builds the CopyObject request that copies src to dest with
+// headers.
+func (s3fs *S3FS) rewriteInput(src string, dest string, headers objectHeaders) *s3.CopyObjectInput {
+	input := &s3.CopyObjectInput{
+		Bucket:               aws.String(s3fs.config.Bucket),
+		CopySource:           aws.String(url.QueryEscape(s3fs.config.Bucket + "/" + s3fs.getKey(src))),
+		CopySourceIfMatch:    optString(headers.ETag),
+		Key:                  aws.String(s3fs.getKey(dest)),
+		MetadataDirective:    types.MetadataDirectiveReplace,
+		Metadata:             headers.Metadata,
+		ContentType:          optString(headers.ContentType),
+		CacheControl:         optString(headers.CacheControl),
+		ContentDisposition:   optString(headers.ContentDisposition),
+		ContentEncoding:      optString(headers.ContentEncoding),
+		ContentLanguage:      optString(headers.ContentLanguage),
+		Expires:              headers.Expires,
+		StorageClass:         headers.StorageClass,
+		ServerSideEncryption: headers.ServerSideEncryption,
+		SSEKMSKeyId:          optString(headers.SSEKMSKeyID),
+	}
+	if headers.ReplaceTags {
+		input.TaggingDirective = types.TaggingDirectiveReplace
+		input.Tagging = aws.String(encodeTags(headers.Tags))
+	}
+	return input
+}
+
+// multipartCopy copies src to dest with UploadPartCopy, which is required
+// for objects larger than maxCopySize.
+func (s3fs *S3FS) multipartCopy(src string, dest string, size int64, headers objectHeaders) error {
+	tags := headers.Tags
+	if !headers.ReplaceTags {
+		var err error
+		if tags, err = s3fs.GetTags(src); err != nil {
+			return err
+		}
+	}
+
+	upload, err := s3fs.s3.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(s3fs.config.Bucket),
+		Key:                  aws.String(s3fs.getKey(dest)),
+		Metadata:             headers.Metadata,
+		ContentType:          optString(headers.ContentType),
+		CacheControl:         optString(headers.CacheControl),
+		ContentDisposition:   optString(headers.ContentDisposition),
+		ContentEncoding:      optString(headers.ContentEncoding),
+		ContentLanguage:      optString(headers.ContentLanguage),
+		Expires:              headers.Expires,
+		StorageClass:         headers.StorageClass,
+		ServerSideEncryption: headers.ServerSideEncryption,
+		SSEKMSKeyId:          optString(headers.SSEKMSKeyID),
+		Tagging:              optString(encodeTags(tags)),
+	})
+	if err != nil {
+		return err
+	}
+
+	parts, err := s3fs.copyParts(src, headers.ETag, upload, size)
+	if err != nil {
+		_, _ = s3fs.s3.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   upload.Bucket,
+			Key:      upload.Key,
+			UploadId: upload.UploadId,
+		})
+		if isPreconditionFailed(err) {
+			return ErrPreconditionFailed
+		}
+		return err
+	}
+
+	_, err = s3fs.s3.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   upload.Bucket,
+		Key:      upload.Key,
+		UploadId: upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: parts,
+		},
+	})
+	return err
+}
+
+// copyParts copies src part by part. Every part is copied on the condition
+// that src still has etag, so that a source replaced mid-copy is not
+// assembled from two versions.
+func (s3fs *S3FS) copyParts(src string, etag string, upload *s3.CreateMultipartUploadOutput, size int64) ([]types.CompletedPart, error) {
+	// Parts may not exceed the part limit of a multipart upload.
+	maxParts := int64(manager.MaxUploadParts)
+	partSize := max(copyPartSize, (size+maxParts-1)/maxParts)
+
+	parts := []types.CompletedPart{}
+	for offset, number := int64(0), int32(1); offset < size; offset, number = offset+partSize, number+1 {
+		end := min(offset+partSize, size) - 1
+		output, err := s3fs.s3.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:            upload.Bucket,
+			Key:               upload.Key,
+			UploadId:          upload.UploadId,
+			PartNumber:        aws.Int32(number),
+			CopySource:        aws.String(url.QueryEscape(s3fs.config.Bucket + "/" + s3fs.getKey(src))),
+			CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+			CopySourceIfMatch: optString(etag),
+		})
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, types.CompletedPart{
+			ETag:       output.CopyPartResult.ETag,
+			PartNumber: aws.Int32(number),
+		})
+	}
+	return parts, nil
+}
+
+func mergeHeaders(head *s3.HeadObjectOutput, update MetadataUpdate) objectHeaders {
+	headers := objectHeaders{
+		Metadata:             map[string]string{},
+		ContentType:          aws.ToString(head.ContentType),
+		CacheControl:         aws.ToString(head.CacheControl),
+		ContentDisposition:   aws.ToString(head.ContentDisposition),
+		ContentEncoding:      aws.ToString(head.ContentEncoding),
+		ContentLanguage:      aws.ToString(head.ContentLanguage),
+		Expires:              head.Expires,
+		StorageClass:         head.StorageClass,
+		ServerSideEncryption: head.ServerSideEncryption,
+		SSEKMSKeyID:          aws.ToString(head.SSEKMSKeyId),
+		ETag:                 aws.ToString(head.ETag),
+	}
+	if !update.ReplaceMetadata {
+		for k, v := range head.Metadata {
+			headers.Metadata[strings.ToLower(k)] = v
+		}
+	}
+	// S3 stores user metadata keys in lower case.
+	for k, v := range update.Metadata {
+		headers.Metadata[strings.ToLower(k)] = v
+	}
+	for _, field := range []struct {
+		dest  *string
+		value string
+	}{
+		{&headers.ContentType, update.ContentType},
+		{&headers.CacheControl, update.CacheControl},
+		{&headers.ContentDisposition, update.ContentDisposition},
+		{&headers.ContentEncoding, update.ContentEncoding},
+		{&headers.ContentLanguage, update.ContentLanguage},
+	} {
+		if field.value != "" {
+			*field.dest = field.value
+		}
+	}
+	return headers
+}
+
+func optString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+func optTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return aws.Time(t)
+}
@@ -0,0 +1,105 @@
+package s3fs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestMergeHeaders(t *testing.T) {
+	head := &s3.HeadObjectOutput{
+		ETag:         aws.String("\"abc\""),
+		ContentType:  aws.String("image/png"),
+		CacheControl: aws.String("max-age=60"),
+		StorageClass: types.StorageClassStandardIa,
+		Metadata: map[string]string{
+			"owner":  "alice",
+			"source": "upload",
+		},
+	}
+
+	t.Run("merge", func(st *testing.T) {
+		headers := mergeHeaders(head, MetadataUpdate{
+			Metadata:     map[string]string{"Owner": "bob"},
+			CacheControl: "no-cache",
+		})
+		if headers.ContentType != "image/png" {
+			st.Fatal("content type should be preserved:", headers.ContentType)
+		}
+		if headers.CacheControl != "no-cache" {
+			st.Fatal("cache control should be updated:", headers.CacheControl)
+		}
+		if headers.StorageClass != types.StorageClassStandardIa {
+			st.Fatal("storage class should be preserved:", headers.StorageClass)
+		}
+		if headers.ETag != "\"abc\"" {
+			st.Fatal("copy should be conditioned on the source ETag:", headers.ETag)
+		}
+		if len(headers.Metadata) != 2 || headers.Metadata["owner"] != "bob" || headers.Metadata["source"] != "upload" {
+			st.Fatal("invalid metadata:", headers.Metadata)
+		}
+	})
+	t.Run("replace", func(st *testing.T) {
+		headers := mergeHeaders(head, MetadataUpdate{
+			Metadata:        map[string]string{"owner": "bob"},
+			ReplaceMetadata: true,
+		})
+		if len(headers.Metadata) != 1 || headers.Metadata["owner"] != "bob" {
+			st.Fatal("invalid metadata:", headers.Metadata)
+		}
+		if headers.ContentType != "image/png" {
+			st.Fatal("content type should be preserved:", headers.ContentType)
+		}
+	})
+}
+
+func TestCopyInput(t *testing.T) {
+	bucket := &S3FS{config: &Config{Bucket: "bucket"}}
+	head := &s3.HeadObjectOutput{
+		ETag:        aws.String("\"abc\""),
+		ContentType: aws.String("image/png"),
+		Metadata:    map[string]string{"owner": "alice"},
+	}
+	input := func(opts CopyOptions) *s3.CopyObjectInput {
+		if opts.MetadataMode == MetadataMerge {
+			return bucket.rewriteInput("/src", "/dest", mergeCopyHeaders(head, opts))
+		}
+		return bucket.copyInput("/src", "/dest", opts)
+	}
+
+	for _, test := range []struct {
+		name        string
+		mode        MetadataMode
+		metadata    map[string]string
+		directive   types.MetadataDirective
+		metadataOut map[string]string
+		contentType string
+	}{
+		{"auto without metadata", MetadataAuto, nil, "", nil, ""},
+		{"auto with metadata", MetadataAuto, map[string]string{"owner": "bob"}, types.MetadataDirectiveReplace, map[string]string{"owner": "bob"}, ""},
+		{"copy", MetadataCopy, map[string]string{"owner": "bob"}, "", nil, ""},
+		{"merge", MetadataMerge, map[string]string{"source": "upload"}, types.MetadataDirectiveReplace, map[string]string{"owner": "alice", "source": "upload"}, "image/png"},
+		{"replace", MetadataReplace, map[string]string{"source": "upload"}, types.MetadataDirectiveReplace, map[string]string{"source": "upload"}, ""},
+		{"replace without metadata", MetadataReplace, nil, types.MetadataDirectiveReplace, nil, ""},
+	} {
+		t.Run(test.name, func(st *testing.T) {
+			in := input(CopyOptions{MetadataMode: test.mode, Metadata: test.metadata})
+			if in.MetadataDirective != test.directive {
+				st.Fatal("invalid directive:", in.MetadataDirective)
+			}
+			if len(in.Metadata) != len(test.metadataOut) {
+				st.Fatal("invalid metadata:", in.Metadata)
+			}
+			for k, v := range test.metadataOut {
+				if in.Metadata[k] != v {
+					st.Fatal("invalid metadata:", in.Metadata)
+				}
+			}
+			if contentType := aws.ToString(in.ContentType); contentType != test.contentType {
+				st.Fatal("invalid content type:", contentType)
+			}
+		})
+	}
+}
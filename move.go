@@ -0,0 +1,383 @@
+package s3fs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// journalPrefix is the reserved directory, inside the tenant, holding the
+// journals of bulk moves that have not completed.
+const journalPrefix = reservedPrefix + "journals/"
+
+type MoveState string
+
+const (
+	// MoveCopying is the state of a move whose sources are all intact.
+	MoveCopying MoveState = "copying"
+	// MoveDeleting is the state of a move whose copies are all verified and
+	// whose sources may be partially deleted.
+	MoveDeleting MoveState = "deleting"
+)
+
+type (
+	MoveJournal struct {
+		ID      string      `json:"id"`
+		Src     string      `json:"src"`
+		Dest    string      `json:"dest"`
+		State   MoveState   `json:"state"`
+		Created time.Time   `json:"created"`
+		Options CopyOptions `json:"options"`
+		Entries []MoveEntry `json:"entries"`
+	}
+	// MoveEntry is an object of a move with the ETag and size its source
+	// had when the move was planned.
+	MoveEntry struct {
+		Src  string `json:"src"`
+		Dest string `json:"dest"`
+		ETag string `json:"etag"`
+		Size int64  `json:"size"`
+		// Skipped is set when a copy precondition failed. The source of a
+		// skipped entry is left in place.
+		Skipped bool `json:"skipped,omitempty"`
+		// Existed is set when the destination already existed when the move
+		// was planned. RollbackMove leaves such destinations in place.
+		Existed bool `json:"existed,omitempty"`
+	}
+	// MoveError reports a bulk move that stopped part way. The move can be
+	// completed with ResumeMove or undone with RollbackMove.
+	MoveError struct {
+		JournalID string
+		Err       error
+	}
+)
+
+var (
+	ErrMoveMismatch = errors.New("destination does not match source")
+	ErrNoJournal    = errors.New("move journal not found")
+)
+
+func (e *MoveError) Error() string {
+	return "move " + e.JournalID + ": " + e.Err.Error()
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+// ResumeMove completes an interrupted bulk move. Objects already copied are
+// not copied again.
+func (s3fs *S3FS) ResumeMove(journalID string) error {
+	journal, err := s3fs.getJournal(journalID)
+	if err != nil {
+		return err
+	}
+	if err := s3fs.runMove(journal); err != nil {
+		return &MoveError{JournalID: journal.ID, Err: err}
+	}
+	return nil
+}
+
+// RollbackMove undoes an interrupted bulk move: sources already deleted are
+// copied back from their destinations, then the destinations the move
+// created are deleted. Destinations that existed before the move, or that no
+// longer match their source, are left alone.
+func (s3fs *S3FS) RollbackMove(journalID string) error {
+	journal, err := s3fs.getJournal(journalID)
+	if err != nil {
+		return err
+	}
+
+	g := &group{}
+	sem := make(chan struct{}, requestConcurrency)
+	for _, entry := range journal.Entries {
+		if entry.Skipped {
+			continue
+		}
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			return s3fs.rollbackEntry(entry)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return &MoveError{JournalID: journal.ID, Err: err}
+	}
+	return s3fs.deleteJournal(journal.ID)
+}
+
+// MoveJournals returns the journals of bulk moves that have not completed.
+func (s3fs *S3FS) MoveJournals() (*[]MoveJournal, error) {
+	journals := []MoveJournal{}
+	err := s3fs.listObjects(journalPrefix, func(obj types.Object) error {
+		id := strings.TrimSuffix(nameOf(*obj.Key), ".json")
+		journal, err := s3fs.getJournal(id)
+		if err != nil {
+			return err
+		}
+		journals = append(journals, *journal)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &journals, nil
+}
+
+// planMove lists the objects under prefix and where they are moved to, and
+// records which destinations already exist.
+func (s3fs *S3FS) planMove(prefix string, dest string, opts CopyOptions) (*MoveJournal, error) {
+	journal := &MoveJournal{
+		ID:      newID(),
+		Src:     prefix,
+		Dest:    dest,
+		State:   MoveCopying,
+		Created: time.Now().UTC(),
+		Options: opts,
+		Entries: []MoveEntry{},
+	}
+	existing := map[string]bool{}
+	_, targetPrefix := s3fs.copyTarget(prefix, dest, s3fs.getKey(prefix))
+	err := s3fs.listObjects(targetPrefix, func(obj types.Object) error {
+		existing[s3fs.pathOf(*obj.Key)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s3fs.listObjects(prefix, func(obj types.Object) error {
+		if s3fs.isReserved(*obj.Key) {
+			return nil
+		}
+		_, target := s3fs.copyTarget(prefix, dest, *obj.Key)
+		journal.Entries = append(journal.Entries, MoveEntry{
+			Src:     s3fs.pathOf(*obj.Key),
+			Dest:    target,
+			ETag:    aws.ToString(obj.ETag),
+			Size:    aws.ToInt64(obj.Size),
+			Existed: existing[target],
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// runMove copies and verifies every entry of journal, then deletes the
+// sources and finally the journal. The journal is updated before the first
+// source is deleted, so an interrupted move never loses an object. Sources
+// are only deleted while they still have the ETag that was copied; a source
+// overwritten during the move is left in place.
+func (s3fs *S3FS) runMove(journal *MoveJournal) error {
+	if journal.State == MoveCopying {
+		g := &group{}
+		sem := make(chan struct{}, requestConcurrency)
+		for i := range journal.Entries {
+			entry := &journal.Entries[i]
+			sem <- struct{}{}
+			g.Go(func() error {
+				defer func() { <-sem }()
+				return s3fs.moveEntry(entry, journal.Options)
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+		journal.State = MoveDeleting
+		if err := s3fs.putJournal(journal); err != nil {
+			return err
+		}
+	}
+
+	objects := []types.ObjectIdentifier{}
+	for _, entry := range journal.Entries {
+		if !entry.Skipped {
+			objects = append(objects, types.ObjectIdentifier{
+				Key:  aws.String(s3fs.getKey(entry.Src)),
+				ETag: aws.String(entry.ETag),
+			})
+		}
+	}
+	if err := dropPreconditionFailed(s3fs.deleteObjects(s3fs.config.Bucket, objects)); err != nil {
+		return err
+	}
+	return s3fs.deleteJournal(journal.ID)
+}
+
+// deleteSource deletes the source of entry if it still has the ETag that was
+// copied.
+func (s3fs *S3FS) deleteSource(entry MoveEntry) error {
+	_, err := s3fs.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(s3fs.config.Bucket),
+		Key:     aws.String(s3fs.getKey(entry.Src)),
+		IfMatch: aws.String(entry.ETag),
+	})
+	if isPreconditionFailed(err) {
+		return nil
+	}
+	return err
+}
+
+// dropPreconditionFailed removes from err the deletes that were rejected
+// because the object changed. It returns nil when no other error is left.
+func dropPreconditionFailed(err error) error {
+	if err == nil {
+		return nil
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	kept := []error{}
+	for _, e := range errs {
+		var deleteErr *DeleteError
+		if errors.As(e, &deleteErr) && isPreconditionFailed(deleteErr) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return errors.Join(kept...)
+}
+
+// moveEntry copies the source of entry unless its destination already
+// matches it. A destination that existed before the move only matches when
+// its ETag can be compared, since a different object may have the same size.
+func (s3fs *S3FS) moveEntry(entry *MoveEntry, opts CopyOptions) error {
+	if s3fs.verifyMove(*entry, entry.Existed) == nil {
+		return nil
+	}
+	var err error
+	if strings.HasSuffix(entry.Src, "/") {
+		err = s3fs.MkDir(entry.Dest)
+	} else {
+		err = s3fs.singleCopy(entry.Src, entry.Dest, opts)
+	}
+	if errors.Is(err, ErrPreconditionFailed) {
+		entry.Skipped = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s3fs.verifyMove(*entry, false)
+}
+
+func (s3fs *S3FS) rollbackEntry(entry MoveEntry) error {
+	_, err := s3fs.s3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(entry.Src)),
+	})
+	deleted := errorCode(err) == "NotFound"
+	if err != nil && !deleted {
+		return err
+	}
+	if !deleted && entry.Existed {
+		return nil
+	}
+	if err := s3fs.verifyMove(entry, false); err != nil {
+		if errors.Is(err, ErrMoveMismatch) || errorCode(err) == "NotFound" {
+			return nil
+		}
+		return err
+	}
+	if deleted {
+		if err := s3fs.singleCopy(entry.Dest, entry.Src, CopyOptions{}); err != nil {
+			return err
+		}
+		restored := MoveEntry{Src: entry.Dest, Dest: entry.Src, ETag: entry.ETag, Size: entry.Size}
+		if err := s3fs.verifyMove(restored, false); err != nil {
+			return err
+		}
+	}
+	if entry.Existed {
+		return nil
+	}
+	_, err = s3fs.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(entry.Dest)),
+	})
+	return err
+}
+
+// verifyMove checks that the destination of entry has the size and ETag its
+// source had. ETags are only compared when both are MD5 digests of the
+// content, which multipart and SSE-KMS ETags are not; when strict is set,
+// ETags that cannot be compared are a mismatch.
+func (s3fs *S3FS) verifyMove(entry MoveEntry, strict bool) error {
+	head, err := s3fs.s3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(entry.Dest)),
+	})
+	if err != nil {
+		return err
+	}
+	if size := aws.ToInt64(head.ContentLength); size != entry.Size {
+		return fmt.Errorf("%w: %s is %d bytes, expected %d", ErrMoveMismatch, entry.Dest, size, entry.Size)
+	}
+	etag := aws.ToString(head.ETag)
+	if !comparableETags(entry.ETag, etag, head.ServerSideEncryption) {
+		if strict {
+			return fmt.Errorf("%w: %s has ETag %s, which cannot be compared with %s", ErrMoveMismatch, entry.Dest, etag, entry.ETag)
+		}
+		return nil
+	}
+	if etag != entry.ETag {
+		return fmt.Errorf("%w: %s has ETag %s, expected %s", ErrMoveMismatch, entry.Dest, etag, entry.ETag)
+	}
+	return nil
+}
+
+func comparableETags(src string, dest string, encryption types.ServerSideEncryption) bool {
+	if strings.Contains(src, "-") || strings.Contains(dest, "-") {
+		return false
+	}
+	return encryption != types.ServerSideEncryptionAwsKms && encryption != types.ServerSideEncryptionAwsKmsDsse
+}
+
+func (s3fs *S3FS) putJournal(journal *MoveJournal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	return s3fs.Put(journalPrefix+journal.ID+".json", io.NopCloser(bytes.NewReader(data)), "application/json")
+}
+
+func (s3fs *S3FS) getJournal(id string) (*MoveJournal, error) {
+	body, err := s3fs.Get(journalPrefix + id + ".json")
+	if errorCode(err) == "NoSuchKey" {
+		return nil, ErrNoJournal
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer (*body).Close()
+	journal := &MoveJournal{}
+	if err := json.NewDecoder(*body).Decode(journal); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+func (s3fs *S3FS) deleteJournal(id string) error {
+	return s3fs.SingleDelete(journalPrefix + id + ".json")
+}
+
+// newID returns a unique ID for a journal or job that sorts by creation
+// time.
+func newID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,94 @@
+package s3fs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestComparableETags(t *testing.T) {
+	t.Run("md5", func(st *testing.T) {
+		if !comparableETags(`"a"`, `"b"`, types.ServerSideEncryptionAes256) {
+			st.Fatal("md5 etags should be compared")
+		}
+	})
+	t.Run("multipart", func(st *testing.T) {
+		if comparableETags(`"a-2"`, `"b"`, "") {
+			st.Fatal("multipart etags should not be compared")
+		}
+		if comparableETags(`"a"`, `"b-3"`, "") {
+			st.Fatal("multipart etags should not be compared")
+		}
+	})
+	t.Run("kms", func(st *testing.T) {
+		if comparableETags(`"a"`, `"b"`, types.ServerSideEncryptionAwsKms) {
+			st.Fatal("kms etags should not be compared")
+		}
+	})
+}
+
+func TestMoveError(t *testing.T) {
+	err := error(&MoveError{JournalID: "id", Err: ErrMoveMismatch})
+	if !errors.Is(err, ErrMoveMismatch) {
+		t.Fatal("move error should unwrap:", err)
+	}
+	var moveErr *MoveError
+	if !errors.As(err, &moveErr) || moveErr.JournalID != "id" {
+		t.Fatal("journal id error:", err)
+	}
+}
+
+func TestDropPreconditionFailed(t *testing.T) {
+	changed := &DeleteError{Path: "/a", Code: "PreconditionFailed"}
+	denied := &DeleteError{Path: "/b", Code: "AccessDenied"}
+	t.Run("only changed", func(st *testing.T) {
+		if err := dropPreconditionFailed(errors.Join(changed)); err != nil {
+			st.Fatal("changed source should be ignored:", err)
+		}
+	})
+	t.Run("mixed", func(st *testing.T) {
+		err := dropPreconditionFailed(errors.Join(changed, denied))
+		var deleteErr *DeleteError
+		if !errors.As(err, &deleteErr) || deleteErr.Path != "/b" {
+			st.Fatal("other failures should be kept:", err)
+		}
+	})
+}
+
+func TestNewID(t *testing.T) {
+	if a, b := newID(), newID(); a == b {
+		t.Fatal("ids should be unique:", a)
+	}
+}
+
+func TestCopyTarget(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config Config
+		prefix string
+		dest   string
+		key    string
+		src    string
+		target string
+	}{
+		{"root", Config{}, "/src/", "/dst/", "src/a", "/src/a", "/dst/src/a"},
+		{"domain", Config{Domain: "t"}, "/src/", "/dst/", "t/src/a", "/src/a", "/dst/src/a"},
+		{"namespace", Config{NameSpace: "app"}, "/src/", "/dst/", "app/src/a", "/src/a", "/dst/src/a"},
+		{"namespace and domain", Config{NameSpace: "app", Domain: "t"}, "/src/", "/dst/", "app/t/src/sub/a", "/src/sub/a", "/dst/src/sub/a"},
+		{"nested prefix", Config{Domain: "t"}, "/a/b/", "/d/", "t/a/b/c", "/a/b/c", "/d/a/b/c"},
+		{"directory marker", Config{Domain: "t"}, "/src/", "/dst/", "t/src/", "/src/", "/dst/src/"},
+	} {
+		t.Run(test.name, func(st *testing.T) {
+			config := test.config
+			tenant := &S3FS{config: &config}
+			src, target := tenant.copyTarget(test.prefix, test.dest, test.key)
+			if src != test.src {
+				st.Fatal("invalid source:", src)
+			}
+			if target != test.target {
+				st.Fatal("invalid target:", target)
+			}
+		})
+	}
+}
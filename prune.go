@@ -0,0 +1,118 @@
+package s3fs
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type (
+	PruneOptions struct {
+		// KeepLast is the number of most recent versions, including the
+		// current one, kept for every key. Delete markers do not count, so
+		// a deleted key keeps its newest versions and can be undeleted.
+		KeepLast int
+		// OlderThan only removes versions that have been noncurrent for
+		// longer than this duration.
+		OlderThan time.Duration
+		// DryRun reports what would be removed without deleting anything.
+		DryRun bool
+	}
+	PruneReport struct {
+		DryRun  bool          `json:"dryRun"`
+		Removed []VersionInfo `json:"removed"`
+		Kept    int           `json:"kept"`
+	}
+)
+
+// PruneVersions deletes the noncurrent versions under prefix that fall
+// outside the retention policy. The current version of a key is never
+// removed. When some deletes fail, the report is returned along with the
+// error, and the versions the error names as not deleted are counted as
+// kept instead of removed.
+func (s3fs *S3FS) PruneVersions(prefix string, opts PruneOptions) (*PruneReport, error) {
+	history, err := s3fs.versionHistory(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	report := planPrune(history, opts, time.Now())
+	report.DryRun = opts.DryRun
+	if opts.DryRun {
+		return report, nil
+	}
+
+	objects := make([]types.ObjectIdentifier, 0, len(report.Removed))
+	for _, v := range report.Removed {
+		objects = append(objects, types.ObjectIdentifier{
+			Key:       aws.String(s3fs.getKey(v.Path)),
+			VersionId: aws.String(v.VersionID),
+		})
+	}
+	if err := s3fs.deleteObjects(s3fs.config.Bucket, objects); err != nil {
+		report.dropFailed(err)
+		return report, err
+	}
+	return report, nil
+}
+
+// dropFailed moves the versions err reports as not deleted from Removed to
+// Kept.
+func (report *PruneReport) dropFailed(err error) {
+	failed := map[[2]string]bool{}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, e := range errs {
+		var deleteErr *DeleteError
+		if errors.As(e, &deleteErr) {
+			failed[[2]string{deleteErr.Path, deleteErr.VersionID}] = true
+		}
+	}
+	removed := report.Removed[:0]
+	for _, v := range report.Removed {
+		if failed[[2]string{v.Path, v.VersionID}] {
+			report.Kept++
+			continue
+		}
+		removed = append(removed, v)
+	}
+	report.Removed = removed
+}
+
+func planPrune(history map[string][]VersionInfo, opts PruneOptions, now time.Time) *PruneReport {
+	report := &PruneReport{
+		Removed: []VersionInfo{},
+	}
+	keepLast := max(opts.KeepLast, 1)
+	for _, versions := range history {
+		kept := 0
+		for i, v := range versions {
+			if v.IsLatest || (!v.IsDeleteMarker && kept < keepLast) {
+				if !v.IsDeleteMarker {
+					kept++
+				}
+				report.Kept++
+				continue
+			}
+			// A version becomes noncurrent when the next one is written.
+			noncurrentSince := versions[i-1].LastModified
+			if opts.OlderThan > 0 && now.Sub(noncurrentSince) <= opts.OlderThan {
+				report.Kept++
+				continue
+			}
+			report.Removed = append(report.Removed, v)
+		}
+	}
+	sort.Slice(report.Removed, func(i, j int) bool {
+		if report.Removed[i].Path != report.Removed[j].Path {
+			return report.Removed[i].Path < report.Removed[j].Path
+		}
+		return report.Removed[i].LastModified.After(report.Removed[j].LastModified)
+	})
+	return report
+}
@@ -0,0 +1,78 @@
+package s3fs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPlanPrune(t *testing.T) {
+	now := time.Now()
+	history := map[string][]VersionInfo{
+		"/file": {
+			{Path: "/file", VersionID: "v4", LastModified: now.Add(-1 * time.Hour), IsLatest: true},
+			{Path: "/file", VersionID: "v3", LastModified: now.Add(-2 * time.Hour)},
+			{Path: "/file", VersionID: "v2", LastModified: now.Add(-48 * time.Hour)},
+			{Path: "/file", VersionID: "v1", LastModified: now.Add(-72 * time.Hour)},
+		},
+	}
+
+	t.Run("keep last", func(st *testing.T) {
+		report := planPrune(history, PruneOptions{KeepLast: 2}, now)
+		if len(report.Removed) != 2 || report.Kept != 2 {
+			st.Fatal("invalid report:", report)
+		}
+		if report.Removed[0].VersionID != "v2" || report.Removed[1].VersionID != "v1" {
+			st.Fatal("invalid removed versions:", report.Removed)
+		}
+	})
+	t.Run("older than", func(st *testing.T) {
+		report := planPrune(history, PruneOptions{OlderThan: 24 * time.Hour}, now)
+		if len(report.Removed) != 1 || report.Removed[0].VersionID != "v1" {
+			st.Fatal("invalid removed versions:", report.Removed)
+		}
+	})
+	t.Run("all noncurrent", func(st *testing.T) {
+		report := planPrune(history, PruneOptions{}, now)
+		if len(report.Removed) != 3 || report.Kept != 1 {
+			st.Fatal("invalid report:", report)
+		}
+	})
+	t.Run("delete marker", func(st *testing.T) {
+		deleted := map[string][]VersionInfo{
+			"/file": {
+				{Path: "/file", VersionID: "m1", LastModified: now.Add(-1 * time.Hour), IsLatest: true, IsDeleteMarker: true},
+				{Path: "/file", VersionID: "v2", LastModified: now.Add(-2 * time.Hour)},
+				{Path: "/file", VersionID: "v1", LastModified: now.Add(-48 * time.Hour)},
+			},
+		}
+		report := planPrune(deleted, PruneOptions{}, now)
+		if len(report.Removed) != 1 || report.Removed[0].VersionID != "v1" {
+			st.Fatal("newest version of a deleted key should be kept:", report.Removed)
+		}
+		if report.Kept != 2 {
+			st.Fatal("invalid report:", report)
+		}
+	})
+}
+
+func TestPruneReportDropFailed(t *testing.T) {
+	report := &PruneReport{
+		Removed: []VersionInfo{
+			{Path: "/file", VersionID: "v1"},
+			{Path: "/file", VersionID: "v2"},
+			{Path: "/other", VersionID: "v1"},
+		},
+		Kept: 1,
+	}
+	report.dropFailed(errors.Join(
+		&DeleteError{Path: "/file", VersionID: "v2", Code: "AccessDenied"},
+		&DeleteError{Path: "/other", VersionID: "v1", Code: "AccessDenied"},
+	))
+	if len(report.Removed) != 1 || report.Removed[0].VersionID != "v1" || report.Removed[0].Path != "/file" {
+		t.Fatal("invalid removed versions:", report.Removed)
+	}
+	if report.Kept != 3 {
+		t.Fatal("failed versions should be kept:", report.Kept)
+	}
+}
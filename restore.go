@@ -0,0 +1,143 @@
+package s3fs
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+type (
+	RestoreOptions struct {
+		// DryRun reports what would be restored without changing anything.
+		DryRun bool
+	}
+	RestoreEntry struct {
+		Path      string `json:"path"`
+		VersionID string `json:"versionId,omitempty"`
+	}
+	RestoreReport struct {
+		At        time.Time      `json:"at"`
+		DryRun    bool           `json:"dryRun"`
+		Restored  []RestoreEntry `json:"restored"`
+		Deleted   []RestoreEntry `json:"deleted"`
+		Unchanged int            `json:"unchanged"`
+	}
+)
+
+var ErrNotDeleted = errors.New("object is not deleted")
+
+// Undelete removes the newest delete marker of key so that the version below
+// it becomes current again.
+func (s3fs *S3FS) Undelete(key string) error {
+	versions, err := s3fs.ListVersions(key)
+	if err != nil {
+		return err
+	}
+	if len(*versions) == 0 || !(*versions)[0].IsDeleteMarker {
+		return ErrNotDeleted
+	}
+	return s3fs.DeleteVersion(key, (*versions)[0].VersionID)
+}
+
+// RestorePrefixAt restores every object under prefix to the version that was
+// current at the given time. Objects that did not exist at that time are
+// deleted. Earlier versions are never removed, so a restore can itself be
+// undone with another RestorePrefixAt.
+func (s3fs *S3FS) RestorePrefixAt(prefix string, at time.Time, opts RestoreOptions) (*RestoreReport, error) {
+	history, err := s3fs.versionHistory(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	report := planRestore(history, at)
+	report.DryRun = opts.DryRun
+	if opts.DryRun {
+		return report, nil
+	}
+
+	g := &group{}
+	sem := make(chan struct{}, requestConcurrency)
+	for _, entry := range report.Restored {
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			return s3fs.RestoreVersion(entry.Path, entry.VersionID)
+		})
+	}
+	for _, entry := range report.Deleted {
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			return s3fs.SingleDelete(entry.Path)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return report, errors.New("some files failed")
+	}
+	return report, nil
+}
+
+// versionHistory groups every version under prefix by path, newest first.
+// Versions of reserved objects are left out.
+func (s3fs *S3FS) versionHistory(prefix string) (map[string][]VersionInfo, error) {
+	history := map[string][]VersionInfo{}
+	err := s3fs.listVersions(prefix, func(v VersionInfo) error {
+		if s3fs.isReserved(s3fs.getKey(v.Path)) {
+			return nil
+		}
+		history[v.Path] = append(history[v.Path], v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, versions := range history {
+		sortVersions(versions)
+	}
+	return history, nil
+}
+
+// versionAt returns the version that was current at the given time, or nil
+// when the key did not exist yet. versions must be sorted newest first.
+func versionAt(versions []VersionInfo, at time.Time) *VersionInfo {
+	for i := range versions {
+		if !versions[i].LastModified.After(at) {
+			return &versions[i]
+		}
+	}
+	return nil
+}
+
+func planRestore(history map[string][]VersionInfo, at time.Time) *RestoreReport {
+	report := &RestoreReport{
+		At:       at,
+		Restored: []RestoreEntry{},
+		Deleted:  []RestoreEntry{},
+	}
+	for path, versions := range history {
+		current := versions[0]
+		target := versionAt(versions, at)
+
+		switch {
+		case target == nil || target.IsDeleteMarker:
+			if current.IsDeleteMarker {
+				report.Unchanged++
+				continue
+			}
+			report.Deleted = append(report.Deleted, RestoreEntry{Path: path})
+		case target.VersionID == current.VersionID:
+			report.Unchanged++
+		default:
+			report.Restored = append(report.Restored, RestoreEntry{
+				Path:      path,
+				VersionID: target.VersionID,
+			})
+		}
+	}
+	for _, entries := range [][]RestoreEntry{report.Restored, report.Deleted} {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Path < entries[j].Path
+		})
+	}
+	return report
+}
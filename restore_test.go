@@ -0,0 +1,65 @@
+package s3fs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVersionAt(t *testing.T) {
+	now := time.Now()
+	versions := []VersionInfo{
+		{VersionID: "v2", LastModified: now},
+		{VersionID: "v1", LastModified: now.Add(-time.Hour)},
+	}
+	if v := versionAt(versions, now.Add(-2*time.Hour)); v != nil {
+		t.Fatal("version should not exist:", v.VersionID)
+	}
+	if v := versionAt(versions, now.Add(-time.Minute)); v == nil || v.VersionID != "v1" {
+		t.Fatal("invalid version:", v)
+	}
+	if v := versionAt(versions, now); v == nil || v.VersionID != "v2" {
+		t.Fatal("invalid version:", v)
+	}
+}
+
+func TestPlanRestore(t *testing.T) {
+	now := time.Now()
+	at := now.Add(-30 * time.Minute)
+	history := map[string][]VersionInfo{
+		"/deleted": {
+			{VersionID: "marker", LastModified: now, IsLatest: true, IsDeleteMarker: true},
+			{VersionID: "v1", LastModified: now.Add(-time.Hour)},
+		},
+		"/overwritten": {
+			{VersionID: "v2", LastModified: now, IsLatest: true},
+			{VersionID: "v1", LastModified: now.Add(-time.Hour)},
+		},
+		"/created": {
+			{VersionID: "v1", LastModified: now, IsLatest: true},
+		},
+		"/untouched": {
+			{VersionID: "v1", LastModified: now.Add(-time.Hour), IsLatest: true},
+		},
+		"/gone": {
+			{VersionID: "marker", LastModified: now.Add(-time.Hour), IsLatest: true, IsDeleteMarker: true},
+			{VersionID: "v1", LastModified: now.Add(-2 * time.Hour)},
+		},
+	}
+	report := planRestore(history, at)
+
+	if len(report.Restored) != 2 {
+		t.Fatal("invalid restored entries:", report.Restored)
+	}
+	if report.Restored[0].Path != "/deleted" || report.Restored[0].VersionID != "v1" {
+		t.Fatal("invalid restored entry:", report.Restored[0])
+	}
+	if report.Restored[1].Path != "/overwritten" || report.Restored[1].VersionID != "v1" {
+		t.Fatal("invalid restored entry:", report.Restored[1])
+	}
+	if len(report.Deleted) != 1 || report.Deleted[0].Path != "/created" {
+		t.Fatal("invalid deleted entries:", report.Deleted)
+	}
+	if report.Unchanged != 2 {
+		t.Fatal("invalid unchanged count:", report.Unchanged)
+	}
+}
@@ -0,0 +1,172 @@
+package s3fs
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+type (
+	Retention struct {
+		// Mode is either types.ObjectLockRetentionModeGovernance or
+		// types.ObjectLockRetentionModeCompliance.
+		Mode        types.ObjectLockRetentionMode
+		RetainUntil time.Time
+	}
+	// DeleteError reports an object that could not be deleted. It matches
+	// ErrObjectLocked with errors.Is when Object Lock prevented the delete,
+	// and unwraps to the SDK error of a failed DeleteObject call.
+	DeleteError struct {
+		Path      string
+		VersionID string
+		Code      string
+		Message   string
+		// Err is the error returned by DeleteObject. It is nil for keys
+		// reported by a DeleteObjects response.
+		Err error
+	}
+)
+
+var ErrObjectLocked = errors.New("object is locked")
+
+func (e *DeleteError) Error() string {
+	msg := "delete " + e.Path
+	if e.VersionID != "" {
+		msg += " (version " + e.VersionID + ")"
+	}
+	return msg + ": " + e.Code + ": " + e.Message
+}
+
+// ErrorCode and ErrorMessage make DeleteError a smithy.APIError, so the S3
+// error code of a wrapped delete failure stays visible to callers.
+func (e *DeleteError) ErrorCode() string {
+	return e.Code
+}
+
+func (e *DeleteError) ErrorMessage() string {
+	return e.Message
+}
+
+func (e *DeleteError) ErrorFault() smithy.ErrorFault {
+	return smithy.FaultUnknown
+}
+
+func (e *DeleteError) Unwrap() []error {
+	errs := []error{}
+	if isLockViolation(e.Code, e.Message) {
+		errs = append(errs, ErrObjectLocked)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// SetDefaultRetention sets the retention applied to every new object version
+// in an Object Lock enabled bucket.
+func (s3fs *S3FS) SetDefaultRetention(mode types.ObjectLockRetentionMode, days int32) error {
+	_, err := s3fs.s3.PutObjectLockConfiguration(ctx, &s3.PutObjectLockConfigurationInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		ObjectLockConfiguration: &types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+			Rule: &types.ObjectLockRule{
+				DefaultRetention: &types.DefaultRetention{
+					Mode: mode,
+					Days: aws.Int32(days),
+				},
+			},
+		},
+	})
+	return err
+}
+
+func (s3fs *S3FS) SetRetention(key string, retention Retention) error {
+	_, err := s3fs.s3.PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(key)),
+		Retention: &types.ObjectLockRetention{
+			Mode:            retention.Mode,
+			RetainUntilDate: aws.Time(retention.RetainUntil),
+		},
+	})
+	return err
+}
+
+func (s3fs *S3FS) GetRetention(key string) (*Retention, error) {
+	output, err := s3fs.s3.GetObjectRetention(ctx, &s3.GetObjectRetentionInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Retention{
+		Mode:        output.Retention.Mode,
+		RetainUntil: aws.ToTime(output.Retention.RetainUntilDate),
+	}, nil
+}
+
+func (s3fs *S3FS) SetLegalHold(key string, on bool) error {
+	status := types.ObjectLockLegalHoldStatusOff
+	if on {
+		status = types.ObjectLockLegalHoldStatusOn
+	}
+	_, err := s3fs.s3.PutObjectLegalHold(ctx, &s3.PutObjectLegalHoldInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(key)),
+		LegalHold: &types.ObjectLockLegalHold{
+			Status: status,
+		},
+	})
+	return err
+}
+
+func (s3fs *S3FS) LegalHold(key string) (bool, error) {
+	output, err := s3fs.s3.GetObjectLegalHold(ctx, &s3.GetObjectLegalHoldInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(key)),
+	})
+	if err != nil {
+		return false, err
+	}
+	return output.LegalHold.Status == types.ObjectLockLegalHoldStatusOn, nil
+}
+
+// deleteError converts an API error returned by DeleteObject into a
+// DeleteError. Other errors are returned unchanged.
+func (s3fs *S3FS) deleteError(key string, versionID string, err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	return &DeleteError{
+		Path:      s3fs.pathOf(key),
+		VersionID: versionID,
+		Code:      apiErr.ErrorCode(),
+		Message:   apiErr.ErrorMessage(),
+		Err:       err,
+	}
+}
+
+// isLockViolation reports whether an S3 error was caused by Object Lock.
+// S3 and MinIO both answer AccessDenied, so the message tells them apart.
+func isLockViolation(code string, message string) bool {
+	if code == "ObjectLocked" {
+		return true
+	}
+	if code != "AccessDenied" {
+		return false
+	}
+	message = strings.ToLower(message)
+	for _, hint := range []string{"object lock", "worm", "retention", "legal hold"} {
+		if strings.Contains(message, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package s3fs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestDeleteError(t *testing.T) {
+	t.Run("locked", func(st *testing.T) {
+		err := error(&DeleteError{
+			Path:    "/file",
+			Code:    "AccessDenied",
+			Message: "Object is WORM protected and cannot be overwritten",
+		})
+		if !errors.Is(err, ErrObjectLocked) {
+			st.Fatal("should be a lock violation:", err)
+		}
+	})
+	t.Run("denied", func(st *testing.T) {
+		err := error(&DeleteError{
+			Path:    "/file",
+			Code:    "AccessDenied",
+			Message: "Access Denied",
+		})
+		if errors.Is(err, ErrObjectLocked) {
+			st.Fatal("should not be a lock violation:", err)
+		}
+	})
+	t.Run("joined", func(st *testing.T) {
+		err := errors.Join(
+			&DeleteError{Path: "/a", Code: "InternalError"},
+			&DeleteError{Path: "/b", Code: "AccessDenied", Message: "object is under legal hold"},
+		)
+		if !errors.Is(err, ErrObjectLocked) {
+			st.Fatal("should contain a lock violation:", err)
+		}
+	})
+	t.Run("version delete", func(st *testing.T) {
+		fs := &S3FS{config: &Config{}}
+		err := fs.deleteError("file", "v1", &smithy.GenericAPIError{
+			Code:    "AccessDenied",
+			Message: "Object is WORM protected and cannot be overwritten",
+		})
+		if !errors.Is(err, ErrObjectLocked) {
+			st.Fatal("should be a lock violation:", err)
+		}
+		err = fs.deleteError("file", "v1", &smithy.GenericAPIError{Code: "NoSuchVersion"})
+		if errorCode(err) != "NoSuchVersion" || retryable(err) {
+			st.Fatal("error code should be kept:", err)
+		}
+	})
+	t.Run("cause", func(st *testing.T) {
+		fs := &S3FS{config: &Config{}}
+		cause := &smithy.GenericAPIError{
+			Code:    "AccessDenied",
+			Message: "Object is WORM protected and cannot be overwritten",
+		}
+		err := fs.deleteError("file", "", cause)
+		var apiErr *smithy.GenericAPIError
+		if !errors.As(err, &apiErr) || apiErr != cause {
+			st.Fatal("should unwrap to the SDK error:", err)
+		}
+		if !errors.Is(err, ErrObjectLocked) {
+			st.Fatal("should still be a lock violation:", err)
+		}
+	})
+}
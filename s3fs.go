@@ -15,12 +15,14 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 )
 
 type (
 	S3FS struct {
-		s3     *s3.Client
-		config *Config
+		s3      *s3.Client
+		config  *Config
+		profile compatProfile
 	}
 	Config struct {
 		NameSpace         string
@@ -32,6 +34,23 @@ type (
 		AccessSecretKey   string
 		EnableMinioCompat bool
 		Endpoint          string
+		// Provider selects the addressing style, checksum behavior, region
+		// handling and API fallbacks for an S3-compatible service. It takes
+		// precedence over EnableMinioCompat and defaults to AWS.
+		Provider Provider
+		// ChecksumAlgorithm opts into end-to-end checksums: Put stores a
+		// checksum of this algorithm with every object and Get verifies the
+		// stored checksum once the body has been read.
+		ChecksumAlgorithm types.ChecksumAlgorithm
+		// RequestChecksumCalculation and ResponseChecksumValidation control
+		// the flexible checksums the SDK sends and validates. When unset
+		// they follow the provider: AWS computes checksums whenever
+		// supported, the other providers only when an operation requires one.
+		RequestChecksumCalculation aws.RequestChecksumCalculation
+		ResponseChecksumValidation aws.ResponseChecksumValidation
+		// JobStore keeps the checkpoints of bulk jobs. It defaults to job
+		// objects in the bucket, under the tenant.
+		JobStore JobStore
 	}
 	FileInfo struct {
 		Name string `json:"name"`
@@ -44,6 +63,58 @@ type (
 		Src  string
 		Dest string
 	}
+	CreateBucketOptions struct {
+		// ObjectLock enables S3 Object Lock on the bucket. It can only be
+		// turned on at creation time and implies versioning.
+		ObjectLock      bool
+		ObjectOwnership types.ObjectOwnership
+		ACL             types.BucketCannedACL
+		Encryption      *BucketEncryption
+		Versioning      bool
+		Tags            map[string]string
+		// Wait is how long to wait for the bucket to exist. It defaults to
+		// five minutes; a negative value does not wait at all.
+		Wait time.Duration
+		// IgnoreExisting treats a bucket that already exists and is owned by
+		// the caller as created, and applies the remaining options to it.
+		IgnoreExisting bool
+	}
+	DeleteBucketOptions struct {
+		// Force empties the bucket before deleting it: every object, version,
+		// delete marker and incomplete multipart upload is removed.
+		Force bool
+		// Progress is called after each batch removed while emptying.
+		Progress func(EmptyProgress)
+	}
+	CopyOptions struct {
+		// MetadataMode selects how Metadata is applied to the copies.
+		MetadataMode MetadataMode
+		Metadata     map[string]string
+		// ReplaceTags sets Tags on the copies instead of copying the source
+		// tags. An empty Tags map copies without any tags.
+		ReplaceTags bool
+		Tags        map[string]string
+		// IfMatch, IfNoneMatch, IfModifiedSince and IfUnmodifiedSince are
+		// preconditions on the source object. A copy whose precondition
+		// fails returns ErrPreconditionFailed; bulk copies skip it instead.
+		IfMatch           string
+		IfNoneMatch       string
+		IfModifiedSince   time.Time
+		IfUnmodifiedSince time.Time
+		// SkipIfIdentical skips objects whose destination already has the
+		// ETag and size of the source, which makes re-running a partially
+		// failed bulk copy cheap.
+		SkipIfIdentical bool
+	}
+	PutOptions struct {
+		ContentType string
+		// Retention locks the new object version until the given time.
+		Retention *Retention
+		// LegalHold places a legal hold on the new object version.
+		LegalHold bool
+		// ChecksumAlgorithm overrides Config.ChecksumAlgorithm.
+		ChecksumAlgorithm types.ChecksumAlgorithm
+	}
 )
 
 const (
@@ -51,9 +122,39 @@ const (
 	File
 )
 
+type MetadataMode int
+
+const (
+	// MetadataAuto copies the source metadata when CopyOptions.Metadata is
+	// nil and behaves like MetadataReplace otherwise.
+	MetadataAuto MetadataMode = iota
+	// MetadataCopy copies the source metadata and headers as-is and ignores
+	// CopyOptions.Metadata.
+	MetadataCopy
+	// MetadataMerge applies CopyOptions.Metadata on top of the source user
+	// metadata and carries over Content-Type, Cache-Control and the other
+	// system headers, as well as the storage class.
+	MetadataMerge
+	// MetadataReplace replaces the user metadata with CopyOptions.Metadata.
+	// System headers of the source are not carried over.
+	MetadataReplace
+)
+
 var ctx = context.TODO()
 
+var ErrPreconditionFailed = errors.New("precondition failed")
+
 func New(config *Config) *S3FS {
+	profile := config.profile()
+	if config.Region == "" && !profile.DefaultLocation {
+		profile.LocationConstraint = false
+	}
+	if config.Region == "" && profile.EndpointRegion != nil {
+		config.Region = profile.EndpointRegion(config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = profile.Region
+	}
 	if config.Region == "" {
 		config.Region = "ap-northeast-1"
 	}
@@ -68,43 +169,89 @@ func New(config *Config) *S3FS {
 		)
 	}
 
-	serv := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		if config.EnableMinioCompat {
-			o.UsePathStyle = config.EnableMinioCompat
-		}
-	})
-
 	if config.Endpoint != "" {
 		cfg.BaseEndpoint = aws.String(config.Endpoint)
 	}
 
+	serv := s3.NewFromConfig(cfg, profile.apply(config))
+
 	return &S3FS{
 		serv,
 		config,
+		profile,
 	}
 }
 
 func (s3fs *S3FS) CreateBucket(name string) error {
-	_, err := s3fs.s3.CreateBucket(ctx, &s3.CreateBucketInput{
-		Bucket: aws.String(name),
-	})
-	if err != nil {
+	return s3fs.CreateBucketWithOptions(name, CreateBucketOptions{})
+}
+
+// CreateBucketWithOptions creates a bucket in Config.Region and applies the
+// given configuration to it once it exists.
+func (s3fs *S3FS) CreateBucketWithOptions(name string, opts CreateBucketOptions) error {
+	input := &s3.CreateBucketInput{
+		Bucket:          aws.String(name),
+		ObjectOwnership: opts.ObjectOwnership,
+		ACL:             opts.ACL,
+	}
+	// Some providers reject the object lock header even when it is false.
+	if opts.ObjectLock {
+		input.ObjectLockEnabledForBucket = aws.Bool(true)
+	}
+	// us-east-1 is the default location and must not be sent explicitly.
+	if s3fs.profile.LocationConstraint && s3fs.config.Region != "us-east-1" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(s3fs.config.Region),
+		}
+	}
+	_, err := s3fs.s3.CreateBucket(ctx, input)
+	if err != nil && !(opts.IgnoreExisting && errorCode(err) == "BucketAlreadyOwnedByYou") {
 		return err
 	}
 
-	w := s3.NewBucketExistsWaiter(s3fs.s3)
+	wait := opts.Wait
+	if wait == 0 {
+		wait = 5 * time.Minute
+	}
+	if wait > 0 {
+		w := s3.NewBucketExistsWaiter(s3fs.s3)
 
-	err = w.Wait(ctx, &s3.HeadBucketInput{
-		Bucket: aws.String(name),
-	}, 5*time.Minute)
-	if err != nil {
-		return err
+		err = w.Wait(ctx, &s3.HeadBucketInput{
+			Bucket: aws.String(name),
+		}, wait)
+		if err != nil {
+			return err
+		}
 	}
 
-	return err
+	if opts.Versioning && !opts.ObjectLock {
+		if err := s3fs.putVersioning(name, types.BucketVersioningStatusEnabled); err != nil {
+			return err
+		}
+	}
+	if opts.Encryption != nil {
+		if err := s3fs.putBucketEncryption(name, *opts.Encryption); err != nil {
+			return err
+		}
+	}
+	if len(opts.Tags) > 0 {
+		if err := s3fs.putBucketTags(name, opts.Tags); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s3fs *S3FS) DeleteBucket(name string) error {
+	return s3fs.DeleteBucketWithOptions(name, DeleteBucketOptions{})
+}
+
+func (s3fs *S3FS) DeleteBucketWithOptions(name string, opts DeleteBucketOptions) error {
+	if opts.Force {
+		if err := s3fs.emptyBucket(name, opts.Progress); err != nil {
+			return err
+		}
+	}
 	_, err := s3fs.s3.DeleteBucket(ctx, &s3.DeleteBucketInput{
 		Bucket: aws.String(name),
 	})
@@ -125,7 +272,7 @@ func (s3fs *S3FS) List(key string) *[]FileInfo {
 			return nil
 		}
 		for _, val := range list.CommonPrefixes {
-			if *val.Prefix == s3fs.getKey("") {
+			if *val.Prefix == s3fs.getKey("") || s3fs.isReserved(*val.Prefix) {
 				continue
 			}
 
@@ -144,7 +291,7 @@ func (s3fs *S3FS) List(key string) *[]FileInfo {
 			if *val.Key == s3fs.getKey("") {
 				continue
 			}
-			if *val.Key == s3fs.getKey(key) {
+			if *val.Key == s3fs.getKey(key) || s3fs.isReserved(*val.Key) {
 				continue
 			}
 
@@ -186,24 +333,65 @@ func (s3fs *S3FS) MkDir(key string) error {
 }
 
 func (s3fs *S3FS) Get(key string) (*io.ReadCloser, error) {
-	output, err := s3fs.s3.GetObject(ctx, &s3.GetObjectInput{
+	body, _, err := s3fs.get(key)
+	if err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
+
+// get returns the body and ETag of key, verifying the body against its
+// stored checksum when Config.ChecksumAlgorithm is set.
+func (s3fs *S3FS) get(key string) (io.ReadCloser, string, error) {
+	input := &s3.GetObjectInput{
 		Bucket: aws.String(s3fs.config.Bucket),
 		Key:    aws.String(s3fs.getKey(key)),
-	})
+	}
+	if s3fs.config.ChecksumAlgorithm != "" {
+		input.ChecksumMode = types.ChecksumModeEnabled
+	}
+	output, err := s3fs.s3.GetObject(ctx, input)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	if s3fs.config.ChecksumAlgorithm != "" {
+		body, err := s3fs.verifyingBody(key, output)
+		if err != nil {
+			output.Body.Close()
+			return nil, "", err
+		}
+		return body, aws.ToString(output.ETag), nil
 	}
-	return &output.Body, nil
+	return output.Body, aws.ToString(output.ETag), nil
 }
 
 func (s3fs *S3FS) Put(key string, body io.ReadCloser, contentType string) error {
-	uploader := manager.NewUploader(s3fs.s3)
-	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+	return s3fs.PutWithOptions(key, body, PutOptions{
+		ContentType: contentType,
+	})
+}
+
+func (s3fs *S3FS) PutWithOptions(key string, body io.ReadCloser, opts PutOptions) error {
+	input := &s3.PutObjectInput{
 		Bucket:      aws.String(s3fs.config.Bucket),
 		Key:         aws.String(s3fs.getKey(key)),
 		Body:        body,
-		ContentType: aws.String(contentType),
-	})
+		ContentType: aws.String(opts.ContentType),
+	}
+	if opts.Retention != nil {
+		input.ObjectLockMode = types.ObjectLockMode(opts.Retention.Mode)
+		input.ObjectLockRetainUntilDate = aws.Time(opts.Retention.RetainUntil)
+	}
+	if opts.LegalHold {
+		input.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+	}
+	input.ChecksumAlgorithm = s3fs.config.ChecksumAlgorithm
+	if opts.ChecksumAlgorithm != "" {
+		input.ChecksumAlgorithm = opts.ChecksumAlgorithm
+	}
+
+	uploader := manager.NewUploader(s3fs.s3)
+	_, err := uploader.Upload(ctx, input)
 	if err != nil {
 		return err
 	}
@@ -224,7 +412,7 @@ func (s3fs *S3FS) SingleDelete(key string) error {
 		Key:    aws.String(s3fs.getKey(key)),
 	})
 	if err != nil {
-		return err
+		return s3fs.deleteError(s3fs.getKey(key), "", err)
 	}
 	return nil
 }
@@ -243,18 +431,15 @@ func (s3fs *S3FS) BulkDelete(prefix string) error {
 
 		objects := []types.ObjectIdentifier{}
 		for _, content := range list.Contents {
+			if s3fs.isReserved(*content.Key) {
+				continue
+			}
 			objects = append(objects, types.ObjectIdentifier{
 				Key: content.Key,
 			})
 		}
 
-		_, err = s3fs.s3.DeleteObjects(ctx, &s3.DeleteObjectsInput{
-			Bucket: aws.String(s3fs.config.Bucket),
-			Delete: &types.Delete{
-				Objects: objects,
-			},
-		})
-
+		err = s3fs.deleteObjects(s3fs.config.Bucket, objects)
 		if err != nil {
 			return err
 		}
@@ -267,38 +452,155 @@ func (s3fs *S3FS) BulkDelete(prefix string) error {
 }
 
 func (s3fs *S3FS) Copy(src string, dest string, metadata map[string]string) error {
+	return s3fs.CopyWithOptions(src, dest, CopyOptions{
+		Metadata: metadata,
+	})
+}
+
+func (s3fs *S3FS) CopyWithOptions(src string, dest string, opts CopyOptions) error {
 	if strings.HasSuffix(src, "/") {
-		return s3fs.BulkCopy(src, dest, metadata)
+		return s3fs.bulkCopy(src, dest, opts)
 	} else {
-		return s3fs.SingleCopy(src, dest, metadata)
+		return s3fs.singleCopy(src, dest, opts)
 	}
 }
 
 func (s3fs *S3FS) SingleCopy(src string, dest string, metadata map[string]string) error {
-	var err error
-	if metadata == nil {
-		_, err = s3fs.s3.CopyObject(ctx, &s3.CopyObjectInput{
-			Bucket:     aws.String(s3fs.config.Bucket),
-			CopySource: aws.String(url.QueryEscape(s3fs.config.Bucket + "/" + s3fs.getKey(src))),
-			Key:        aws.String(s3fs.getKey(dest)),
-		})
-	} else {
-		_, err = s3fs.s3.CopyObject(ctx, &s3.CopyObjectInput{
+	return s3fs.singleCopy(src, dest, CopyOptions{
+		Metadata: metadata,
+	})
+}
+
+func (s3fs *S3FS) singleCopy(src string, dest string, opts CopyOptions) error {
+	if opts.SkipIfIdentical {
+		identical, err := s3fs.identical(src, dest)
+		if err != nil {
+			return err
+		}
+		if identical {
+			return nil
+		}
+	}
+
+	if opts.MetadataMode == MetadataMerge {
+		head, err := s3fs.s3.HeadObject(ctx, &s3.HeadObjectInput{
 			Bucket:            aws.String(s3fs.config.Bucket),
-			CopySource:        aws.String(url.QueryEscape(s3fs.config.Bucket + "/" + s3fs.getKey(src))),
-			Key:               aws.String(s3fs.getKey(dest)),
-			Metadata:          metadata,
-			MetadataDirective: types.MetadataDirectiveReplace,
+			Key:               aws.String(s3fs.getKey(src)),
+			IfMatch:           optString(opts.IfMatch),
+			IfNoneMatch:       optString(opts.IfNoneMatch),
+			IfModifiedSince:   optTime(opts.IfModifiedSince),
+			IfUnmodifiedSince: optTime(opts.IfUnmodifiedSince),
 		})
+		if isPreconditionFailed(err) {
+			return ErrPreconditionFailed
+		}
+		if err != nil {
+			return err
+		}
+		return s3fs.rewriteObject(src, dest, aws.ToInt64(head.ContentLength), mergeCopyHeaders(head, opts))
 	}
 
+	_, err := s3fs.s3.CopyObject(ctx, s3fs.copyInput(src, dest, opts))
+	if isPreconditionFailed(err) {
+		return ErrPreconditionFailed
+	}
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// copyInput builds the CopyObject request of a copy in any MetadataMode but
+// MetadataMerge, which rewrites the object with mergeCopyHeaders instead.
+func (s3fs *S3FS) copyInput(src string, dest string, opts CopyOptions) *s3.CopyObjectInput {
+	input := &s3.CopyObjectInput{
+		Bucket:                      aws.String(s3fs.config.Bucket),
+		CopySource:                  aws.String(url.QueryEscape(s3fs.config.Bucket + "/" + s3fs.getKey(src))),
+		Key:                         aws.String(s3fs.getKey(dest)),
+		CopySourceIfMatch:           optString(opts.IfMatch),
+		CopySourceIfNoneMatch:       optString(opts.IfNoneMatch),
+		CopySourceIfModifiedSince:   optTime(opts.IfModifiedSince),
+		CopySourceIfUnmodifiedSince: optTime(opts.IfUnmodifiedSince),
+	}
+	if opts.MetadataMode == MetadataReplace || (opts.MetadataMode == MetadataAuto && opts.Metadata != nil) {
+		input.Metadata = opts.Metadata
+		input.MetadataDirective = types.MetadataDirectiveReplace
+	}
+	if opts.ReplaceTags {
+		input.TaggingDirective = types.TaggingDirectiveReplace
+		input.Tagging = aws.String(encodeTags(opts.Tags))
+	}
+	return input
+}
+
+// mergeCopyHeaders returns the headers of a MetadataMerge copy of the object
+// described by head.
+func mergeCopyHeaders(head *s3.HeadObjectOutput, opts CopyOptions) objectHeaders {
+	headers := mergeHeaders(head, MetadataUpdate{
+		Metadata: opts.Metadata,
+	})
+	headers.ReplaceTags = opts.ReplaceTags
+	headers.Tags = opts.Tags
+	return headers
+}
+
+// identical reports whether dest already exists with the content of src.
+func (s3fs *S3FS) identical(src string, dest string) (bool, error) {
+	srcInfo, err := s3fs.s3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s3fs.config.Bucket),
+		Key:          aws.String(s3fs.getKey(src)),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return false, err
+	}
+	destInfo, err := s3fs.s3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s3fs.config.Bucket),
+		Key:          aws.String(s3fs.getKey(dest)),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return false, nil
+	}
+	return sameContent(srcInfo, destInfo), nil
+}
+
+// sameContent reports whether two objects of the same size hold the same
+// content. ETags are compared when both are MD5 digests of the content.
+// Multipart and SSE-KMS ETags are not, and a copy gets a different one, so
+// those objects match when their ETags or a stored checksum are equal.
+func sameContent(src *s3.HeadObjectOutput, dest *s3.HeadObjectOutput) bool {
+	if aws.ToInt64(src.ContentLength) != aws.ToInt64(dest.ContentLength) {
+		return false
+	}
+	srcETag, destETag := aws.ToString(src.ETag), aws.ToString(dest.ETag)
+	if comparableETags(srcETag, destETag, src.ServerSideEncryption) && comparableETags(srcETag, destETag, dest.ServerSideEncryption) {
+		return srcETag == destETag
+	}
+	if srcETag != "" && srcETag == destETag {
+		return true
+	}
+	for _, checksums := range [][2]*string{
+		{src.ChecksumCRC32, dest.ChecksumCRC32},
+		{src.ChecksumCRC32C, dest.ChecksumCRC32C},
+		{src.ChecksumCRC64NVME, dest.ChecksumCRC64NVME},
+		{src.ChecksumSHA1, dest.ChecksumSHA1},
+		{src.ChecksumSHA256, dest.ChecksumSHA256},
+	} {
+		if sum := aws.ToString(checksums[0]); sum != "" && sum == aws.ToString(checksums[1]) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s3fs *S3FS) BulkCopy(prefix string, dest string, metadata map[string]string) error {
+	return s3fs.bulkCopy(prefix, dest, CopyOptions{
+		Metadata: metadata,
+	})
+}
+
+func (s3fs *S3FS) bulkCopy(prefix string, dest string, opts CopyOptions) error {
 	var continuationToken *string
 	for {
 		list, err := s3fs.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
@@ -310,57 +612,88 @@ func (s3fs *S3FS) BulkCopy(prefix string, dest string, metadata map[string]strin
 			return err
 		}
 
-		k := strings.Split(prefix, "/")
-		currentKey := k[len(k)-1]
-		baseKey := strings.TrimSuffix(prefix, currentKey+"/")
-
-		var result error
-		wg := &sync.WaitGroup{}
+		g := &group{}
+		sem := make(chan struct{}, requestConcurrency)
 		for _, content := range list.Contents {
-			wg.Add(1)
-			go func(c types.Object) {
-				srcRel := strings.Replace(*c.Key, s3fs.config.Domain, "", 1)
-				destRel := strings.Replace(dest, s3fs.config.Domain, "", 1)
-				targetPath := destRel + strings.TrimPrefix(srcRel, baseKey)
+			if s3fs.isReserved(*content.Key) {
+				continue
+			}
+			sem <- struct{}{}
+			g.Go(func() error {
+				defer func() { <-sem }()
+				srcRel, targetPath := s3fs.copyTarget(prefix, dest, *content.Key)
 
-				var e error
+				var err error
 				if strings.HasSuffix(srcRel, "/") {
-					e = s3fs.MkDir(targetPath)
+					err = s3fs.MkDir(targetPath)
 				} else {
-					e = s3fs.SingleCopy(srcRel, targetPath, metadata)
+					err = s3fs.singleCopy(srcRel, targetPath, opts)
 				}
-
-				if e != nil {
-					result = e
+				if errors.Is(err, ErrPreconditionFailed) {
+					return nil
 				}
-
-				wg.Done()
-			}(content)
+				return err
+			})
 		}
-		wg.Wait()
-
-		if result != nil {
+		if err := g.Wait(); err != nil {
 			return errors.New("some files failed")
 		}
 
 		if *list.IsTruncated {
-			continuationToken = list.ContinuationToken
+			continuationToken = list.NextContinuationToken
 		} else {
 			return nil
 		}
 	}
 }
 
+// copyTarget returns the source path of key and the path it is copied to
+// when prefix is copied into dest. Keys are taken relative to the root of
+// the tenant, so /a/b/c copied from /a/b/ into /d/ becomes /d/a/b/c.
+func (s3fs *S3FS) copyTarget(prefix string, dest string, key string) (string, string) {
+	k := strings.Split(prefix, "/")
+	currentKey := k[len(k)-1]
+	baseKey := strings.TrimSuffix(prefix, currentKey+"/")
+
+	srcRel := strings.TrimPrefix(key, s3fs.getKey(""))
+	return s3fs.pathOf(key), dest + strings.TrimPrefix(srcRel, baseKey)
+}
+
 func (s3fs *S3FS) Move(src string, dest string) error {
+	return s3fs.MoveWithOptions(src, dest, CopyOptions{})
+}
+
+func (s3fs *S3FS) MoveWithOptions(src string, dest string, opts CopyOptions) error {
 	if strings.HasSuffix(src, "/") {
-		return s3fs.BulkMove(src, dest)
+		return s3fs.bulkMove(src, dest, opts)
 	} else {
-		return s3fs.SingleMove(src, dest)
+		return s3fs.singleMove(src, dest, opts)
 	}
 }
 
 func (s3fs *S3FS) SingleMove(src string, dest string) error {
-	if err := s3fs.Copy(src, dest, nil); err != nil {
+	return s3fs.singleMove(src, dest, CopyOptions{})
+}
+
+// singleMove deletes src only after verifying that dest matches it.
+func (s3fs *S3FS) singleMove(src string, dest string, opts CopyOptions) error {
+	head, err := s3fs.s3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(src)),
+	})
+	if err != nil {
+		return err
+	}
+	entry := MoveEntry{
+		Src:  src,
+		Dest: dest,
+		ETag: aws.ToString(head.ETag),
+		Size: aws.ToInt64(head.ContentLength),
+	}
+	if err := s3fs.singleCopy(src, dest, opts); err != nil {
+		return err
+	}
+	if err := s3fs.verifyMove(entry, false); err != nil {
 		return err
 	}
 	if err := s3fs.Delete(src); err != nil {
@@ -370,12 +703,25 @@ func (s3fs *S3FS) SingleMove(src string, dest string) error {
 }
 
 func (s3fs *S3FS) BulkMove(prefix string, dest string) error {
-	if err := s3fs.BulkCopy(prefix, dest, nil); err != nil {
+	return s3fs.bulkMove(prefix, dest, CopyOptions{})
+}
+
+// bulkMove records the move in a journal before copying, so that it can be
+// resumed or rolled back with the journal ID of the returned *MoveError.
+func (s3fs *S3FS) bulkMove(prefix string, dest string, opts CopyOptions) error {
+	journal, err := s3fs.planMove(prefix, dest, opts)
+	if err != nil {
 		return err
 	}
-	if err := s3fs.BulkDelete(prefix); err != nil {
+	if len(journal.Entries) == 0 {
+		return nil
+	}
+	if err := s3fs.putJournal(journal); err != nil {
 		return err
 	}
+	if err := s3fs.runMove(journal); err != nil {
+		return &MoveError{JournalID: journal.ID, Err: err}
+	}
 	return nil
 }
 
@@ -387,6 +733,33 @@ func (s3fs *S3FS) Info(key string) *s3.HeadObjectOutput {
 	return result
 }
 
+// Walk calls fn for every file and directory marker under key, in key order.
+// Returning an error from fn stops the walk and returns that error.
+func (s3fs *S3FS) Walk(key string, fn func(FileInfo) error) error {
+	return s3fs.listObjects(key, func(obj types.Object) error {
+		if *obj.Key == s3fs.getKey("") || s3fs.isReserved(*obj.Key) {
+			return nil
+		}
+		return fn(s3fs.fileInfo(*obj.Key, aws.ToInt64(obj.Size)))
+	})
+}
+
+func (s3fs *S3FS) fileInfo(key string, size int64) FileInfo {
+	if strings.HasSuffix(key, "/") {
+		return FileInfo{
+			Type: Directory,
+			Name: nameOf(key),
+			Path: s3fs.pathOf(key),
+		}
+	}
+	return FileInfo{
+		Type: File,
+		Name: nameOf(key),
+		Path: s3fs.pathOf(key),
+		Size: size,
+	}
+}
+
 func (s3fs *S3FS) getKey(key string) string {
 	k := ""
 	if s3fs.config.NameSpace != "" {
@@ -400,6 +773,158 @@ func (s3fs *S3FS) getKey(key string) string {
 	return k + key
 }
 
+func (s3fs *S3FS) listObjects(prefix string, fn func(types.Object) error) error {
+	var continuationToken *string
+	for {
+		list, err := s3fs.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(s3fs.config.Bucket),
+			Prefix:            aws.String(s3fs.getKey(prefix)),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return err
+		}
+		for _, content := range list.Contents {
+			if err := fn(content); err != nil {
+				return err
+			}
+		}
+		if !aws.ToBool(list.IsTruncated) {
+			return nil
+		}
+		continuationToken = list.NextContinuationToken
+	}
+}
+
+// deleteObjects removes objects from bucket in batches of up to 1000 keys,
+// the most a single DeleteObjects call accepts.
+func (s3fs *S3FS) deleteObjects(bucket string, objects []types.ObjectIdentifier) error {
+	if !s3fs.profile.MultiObjectDelete {
+		return s3fs.deleteEach(bucket, objects)
+	}
+	for len(objects) > 0 {
+		n := min(len(objects), 1000)
+		output, err := s3fs.s3.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{
+				Objects: objects[:n],
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if len(output.Errors) > 0 {
+			errs := make([]error, 0, len(output.Errors))
+			for _, e := range output.Errors {
+				errs = append(errs, &DeleteError{
+					Path:      s3fs.pathOf(aws.ToString(e.Key)),
+					VersionID: aws.ToString(e.VersionId),
+					Code:      aws.ToString(e.Code),
+					Message:   aws.ToString(e.Message),
+				})
+			}
+			return errors.Join(errs...)
+		}
+		objects = objects[n:]
+	}
+	return nil
+}
+
+// deleteEach removes objects one DeleteObject call at a time for providers
+// without DeleteObjects, reporting failures like deleteObjects does.
+func (s3fs *S3FS) deleteEach(bucket string, objects []types.ObjectIdentifier) error {
+	g := &group{}
+	sem := make(chan struct{}, requestConcurrency)
+	var mu sync.Mutex
+	errs := []error{}
+	for _, object := range objects {
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			_, err := s3fs.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket:    aws.String(bucket),
+				Key:       object.Key,
+				VersionId: object.VersionId,
+				IfMatch:   object.ETag,
+			})
+			if err == nil {
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, s3fs.deleteError(aws.ToString(object.Key), aws.ToString(object.VersionId), err))
+			return err
+		})
+	}
+	if g.Wait() != nil {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// errorCode returns the S3 error code of err, or an empty string when err is
+// not an API error.
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+// isPreconditionFailed reports whether a conditional request was rejected.
+// HeadObject answers 304 for If-None-Match and If-Modified-Since, and S3
+// answers 409 when a concurrent conditional write wins the race.
+func isPreconditionFailed(err error) bool {
+	switch errorCode(err) {
+	case "PreconditionFailed", "NotModified", "ConditionalRequestConflict":
+		return true
+	}
+	return false
+}
+
+// requestConcurrency bounds the requests a bulk operation sends at once.
+const requestConcurrency = 16
+
+// group runs functions concurrently and keeps the last error they return.
+type group struct {
+	wg  sync.WaitGroup
+	mu  sync.Mutex
+	err error
+}
+
+func (g *group) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(); err != nil {
+			g.mu.Lock()
+			g.err = err
+			g.mu.Unlock()
+		}
+	}()
+}
+
+// Err returns the last error returned so far, without waiting.
+func (g *group) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+func (g *group) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
+
+func dirKey(key string) string {
+	if !strings.HasSuffix(key, "/") {
+		key += "/"
+	}
+	return key
+}
+
 func (s3fs *S3FS) PathExists(key string) bool {
 	list, err := s3fs.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
 		Bucket:    aws.String(s3fs.config.Bucket),
@@ -2,9 +2,18 @@ package s3fs
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
 )
 
 var fs *S3FS
@@ -343,6 +352,219 @@ func TestS3FS_Move(t *testing.T) {
 	})
 }
 
+func TestS3FS_ConditionalPut(t *testing.T) {
+	var etag string
+	t.Run("put if absent", func(st *testing.T) {
+		var err error
+		etag, err = fs.PutIfAbsent("/lockfile", ioutil.NopCloser(bytes.NewReader([]byte("first"))))
+		if err != nil {
+			st.Fatal("put error:", err)
+		}
+		_, err = fs.PutIfAbsent("/lockfile", ioutil.NopCloser(bytes.NewReader([]byte("second"))))
+		if !errors.Is(err, ErrPreconditionFailed) {
+			st.Fatal("overwrite error:", err)
+		}
+	})
+	t.Run("put if match", func(st *testing.T) {
+		body, current, err := fs.GetWithETag("/lockfile")
+		if err != nil {
+			st.Fatal("get error:", err)
+		}
+		defer (*body).Close()
+		if current != etag {
+			st.Fatal("etag error:", current, etag)
+		}
+		if _, err := fs.PutIfMatch("/lockfile", ioutil.NopCloser(bytes.NewReader([]byte("third"))), etag); err != nil {
+			st.Fatal("put error:", err)
+		}
+		_, err = fs.PutIfMatch("/lockfile", ioutil.NopCloser(bytes.NewReader([]byte("fourth"))), etag)
+		if !errors.Is(err, ErrPreconditionFailed) {
+			st.Fatal("stale etag error:", err)
+		}
+	})
+}
+
+func TestS3FS_Elect(t *testing.T) {
+	leader, err := fs.Elect(context.Background(), "leader", "a")
+	if err != nil {
+		t.Fatal("elect error:", err)
+	}
+	t.Run("follower waits", func(st *testing.T) {
+		c, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if _, err := fs.Elect(c, "leader", "b"); !errors.Is(err, context.DeadlineExceeded) {
+			st.Fatal("follower error:", err)
+		}
+	})
+	t.Run("lock is not listed", func(st *testing.T) {
+		for _, info := range *fs.List("/") {
+			if info.Path == reservedPrefix {
+				st.Fatal("reserved directory listed:", info.Path)
+			}
+		}
+	})
+	t.Run("resign", func(st *testing.T) {
+		if err := leader.Resign(); err != nil {
+			st.Fatal("resign error:", err)
+		}
+		select {
+		case <-leader.Lost():
+		default:
+			st.Fatal("leadership should be lost after resigning")
+		}
+		next, err := fs.Elect(context.Background(), "leader", "b")
+		if err != nil {
+			st.Fatal("elect error:", err)
+		}
+		if next.Lease().Owner != "b" {
+			st.Fatal("owner error:", next.Lease().Owner)
+		}
+		_ = next.Resign()
+	})
+}
+
+func TestS3FS_Job(t *testing.T) {
+	store := &memoryJobStore{}
+	config := *fs.config
+	config.Domain = "jobs"
+	config.JobStore = store
+	jobs := fs.view(&config)
+	for _, key := range []string{"/src/a", "/src/b"} {
+		if err := jobs.Put(key, ioutil.NopCloser(bytes.NewReader([]byte("job"))), "text/plain"); err != nil {
+			t.Fatal("put error:", err)
+		}
+	}
+
+	job := &Job{ID: newID(), Kind: JobMove, Src: "/src/", Dest: "/dst/", State: JobRunning}
+	t.Run("pause", func(st *testing.T) {
+		stop := make(chan struct{})
+		close(stop)
+		if err := jobs.processJob(job, stop); err != nil {
+			st.Fatal("process error:", err)
+		}
+		if checkpoint, _ := store.Load(job.ID); checkpoint == nil || checkpoint.State != JobPaused {
+			st.Fatal("checkpoint error:", checkpoint)
+		}
+		if jobs.Info("/src/a") == nil || jobs.Info("/dst/src/a") != nil {
+			st.Fatal("paused job should not move anything")
+		}
+	})
+	t.Run("resume from checkpoint", func(st *testing.T) {
+		job.State = JobRunning
+		job.Completed = []string{jobs.getKey("/src/a")}
+		if err := jobs.processJob(job, make(chan struct{})); err != nil {
+			st.Fatal("process error:", err)
+		}
+		if job.State != JobDone || job.Processed != 1 || len(job.Completed) != 0 {
+			st.Fatal("job error:", job)
+		}
+		if jobs.Info("/src/b") != nil || jobs.Info("/dst/src/b") == nil {
+			st.Fatal("pending key should be moved")
+		}
+		if jobs.Info("/src/a") == nil || jobs.Info("/dst/src/a") != nil {
+			st.Fatal("completed key should be skipped")
+		}
+	})
+	t.Run("checkpoint", func(st *testing.T) {
+		for i := range jobCheckpointEvery {
+			if err := jobs.Put(fmt.Sprintf("/many/%03d", i), ioutil.NopCloser(bytes.NewReader([]byte("job"))), "text/plain"); err != nil {
+				st.Fatal("put error:", err)
+			}
+		}
+		job := &Job{ID: newID(), Kind: JobCopy, Src: "/many/", Dest: "/copy/", State: JobRunning}
+		if err := jobs.processJob(job, make(chan struct{})); err != nil {
+			st.Fatal("process error:", err)
+		}
+		checkpointed := false
+		for _, checkpoint := range store.checkpoints {
+			if checkpoint.ID == job.ID && checkpoint.State == JobRunning && len(checkpoint.Completed) == jobCheckpointEvery {
+				checkpointed = true
+			}
+		}
+		if !checkpointed {
+			st.Fatal("no checkpoint within the page")
+		}
+		if job.State != JobDone || job.Processed != jobCheckpointEvery {
+			st.Fatal("job error:", job)
+		}
+		if jobs.Info("/copy/many/000") == nil {
+			st.Fatal("copy error")
+		}
+	})
+	t.Run("checkpoint error", func(st *testing.T) {
+		saveErr := errors.New("store unavailable")
+		store.mu.Lock()
+		store.err = saveErr
+		store.mu.Unlock()
+		defer func() {
+			store.mu.Lock()
+			store.err = nil
+			store.mu.Unlock()
+		}()
+		job := &Job{ID: newID(), Kind: JobCopy, Src: "/many/", Dest: "/copy2/", State: JobRunning}
+		if err := jobs.processJob(job, make(chan struct{})); !errors.Is(err, saveErr) {
+			st.Fatal("checkpoint error should fail the job:", err)
+		}
+		if job.State != JobFailed || len(job.Errors) == 0 || !strings.Contains(job.Errors[len(job.Errors)-1], "checkpoint") {
+			st.Fatal("job error:", job)
+		}
+	})
+	t.Run("ResumeJob", func(st *testing.T) {
+		job := &Job{ID: newID(), Kind: JobDelete, Src: "/many/", State: JobPaused}
+		if err := store.Save(job); err != nil {
+			st.Fatal("save error:", err)
+		}
+		if err := jobs.ResumeJob(job.ID); err != nil {
+			st.Fatal("resume error:", err)
+		}
+		if err := jobs.WaitJob(job.ID); err != nil {
+			st.Fatal("job error:", err)
+		}
+		status, err := jobs.JobStatus(job.ID)
+		if err != nil {
+			st.Fatal("status error:", err)
+		}
+		if status.State != JobDone || status.Processed != jobCheckpointEvery {
+			st.Fatal("job error:", status)
+		}
+		if jobs.Info("/many/000") != nil {
+			st.Fatal("delete error")
+		}
+	})
+}
+
+func TestS3FS_Batch(t *testing.T) {
+	batch := fs.Sub("batch")
+	t.Run("malformed manifest", func(st *testing.T) {
+		if err := batch.Put("/a", ioutil.NopCloser(bytes.NewReader([]byte("batch"))), "text/plain"); err != nil {
+			st.Fatal("put error:", err)
+		}
+		report, err := batch.RunBatch(strings.NewReader("/a\n/b,v1,/c,extra\n"), BatchOp{Kind: BatchDelete})
+		if report != nil {
+			// Reports are reserved objects, which Delete skips, so the bucket
+			// could not be deleted afterwards.
+			st.Cleanup(func() {
+				_, _ = batch.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+					Bucket: aws.String(batch.config.Bucket),
+					Key:    aws.String(batch.getKey(report.ReportKey)),
+				})
+			})
+		}
+		if !errors.Is(err, ErrInvalidManifest) {
+			st.Fatal("batch error:", err)
+		}
+		if report == nil || report.Total != 1 || report.Succeeded != 1 {
+			st.Fatal("partial report error:", report)
+		}
+		if batch.Info(report.ReportKey) == nil {
+			st.Fatal("partial report should be written")
+		}
+		if batch.Info("/a") != nil {
+			st.Fatal("entries before the malformed one should be applied")
+		}
+	})
+}
+
 func TestS3FS_Delete(t *testing.T) {
 	t.Run("rm", func(st *testing.T) {
 		if err := fs.Delete("/testfile"); err != nil {
@@ -380,3 +602,18 @@ func TestS3FS_DeleteBucket(t *testing.T) {
 		}
 	})
 }
+
+func TestIsPreconditionFailed(t *testing.T) {
+	if !isPreconditionFailed(&smithy.GenericAPIError{Code: "PreconditionFailed"}) {
+		t.Fatal("412 should be a failed precondition")
+	}
+	if !isPreconditionFailed(&smithy.GenericAPIError{Code: "NotModified"}) {
+		t.Fatal("304 should be a failed precondition")
+	}
+	if isPreconditionFailed(&smithy.GenericAPIError{Code: "NoSuchKey"}) {
+		t.Fatal("404 should not be a failed precondition")
+	}
+	if isPreconditionFailed(errors.New("network error")) {
+		t.Fatal("non API errors should not be a failed precondition")
+	}
+}
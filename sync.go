@@ -0,0 +1,154 @@
+package s3fs
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type (
+	DiffEntry struct {
+		Path     string `json:"path"`
+		SrcETag  string `json:"srcETag,omitempty"`
+		SrcSize  int64  `json:"srcSize,omitempty"`
+		DestETag string `json:"destETag,omitempty"`
+		DestSize int64  `json:"destSize,omitempty"`
+	}
+	DiffResult struct {
+		Added   []DiffEntry `json:"added"`
+		Removed []DiffEntry `json:"removed"`
+		Changed []DiffEntry `json:"changed"`
+	}
+	SyncOptions struct {
+		// Delete removes objects that exist only under the destination prefix.
+		Delete bool
+		// DryRun computes the diff without copying or deleting anything.
+		DryRun bool
+	}
+)
+
+// Diff compares the objects under srcPrefix with those under dstPrefix.
+// Entries are matched by their path relative to each prefix and are reported
+// as changed when their ETag or size differ.
+func (s3fs *S3FS) Diff(srcPrefix string, dstPrefix string) (*DiffResult, error) {
+	src, err := s3fs.listRelative(srcPrefix)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := s3fs.listRelative(dstPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return diffObjects(src, dst), nil
+}
+
+// SyncPrefix brings dstPrefix in line with srcPrefix using server-side copies
+// and, when opts.Delete is set, deletes. It returns the diff it applied.
+func (s3fs *S3FS) SyncPrefix(srcPrefix string, dstPrefix string, opts SyncOptions) (*DiffResult, error) {
+	diff, err := s3fs.Diff(srcPrefix, dstPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return diff, nil
+	}
+
+	// Unlike BulkCopy, which places the source directory inside dest, a sync
+	// mirrors srcPrefix onto dstPrefix: the entry at a relative path under
+	// one maps to the same relative path under the other. This is the
+	// mapping Diff compares by, so copyTarget is not used here.
+	srcPrefix = dirKey(srcPrefix)
+	dstPrefix = dirKey(dstPrefix)
+
+	g := &group{}
+	sem := make(chan struct{}, requestConcurrency)
+	for _, entries := range [][]DiffEntry{diff.Added, diff.Changed} {
+		for _, entry := range entries {
+			p := entry.Path
+			sem <- struct{}{}
+			g.Go(func() error {
+				defer func() { <-sem }()
+				if strings.HasSuffix(p, "/") {
+					return s3fs.MkDir(dstPrefix + p)
+				}
+				return s3fs.SingleCopy(srcPrefix+p, dstPrefix+p, nil)
+			})
+		}
+	}
+	if opts.Delete {
+		for _, entry := range diff.Removed {
+			p := entry.Path
+			sem <- struct{}{}
+			g.Go(func() error {
+				defer func() { <-sem }()
+				return s3fs.SingleDelete(dstPrefix + p)
+			})
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return diff, errors.New("some files failed")
+	}
+	return diff, nil
+}
+
+// listRelative lists every object under prefix keyed by its path relative to
+// the prefix. The directory marker of the prefix itself is skipped.
+func (s3fs *S3FS) listRelative(prefix string) (map[string]types.Object, error) {
+	prefix = dirKey(prefix)
+	objects := map[string]types.Object{}
+	err := s3fs.listObjects(prefix, func(obj types.Object) error {
+		rel := strings.TrimPrefix(*obj.Key, s3fs.getKey(prefix))
+		if rel != "" && !s3fs.isReserved(*obj.Key) {
+			objects[rel] = obj
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func diffObjects(src map[string]types.Object, dst map[string]types.Object) *DiffResult {
+	result := &DiffResult{
+		Added:   []DiffEntry{},
+		Removed: []DiffEntry{},
+		Changed: []DiffEntry{},
+	}
+	for p, s := range src {
+		entry := DiffEntry{
+			Path:    p,
+			SrcETag: aws.ToString(s.ETag),
+			SrcSize: aws.ToInt64(s.Size),
+		}
+		d, ok := dst[p]
+		if !ok {
+			result.Added = append(result.Added, entry)
+			continue
+		}
+		entry.DestETag = aws.ToString(d.ETag)
+		entry.DestSize = aws.ToInt64(d.Size)
+		if entry.SrcETag != entry.DestETag || entry.SrcSize != entry.DestSize {
+			result.Changed = append(result.Changed, entry)
+		}
+	}
+	for p, d := range dst {
+		if _, ok := src[p]; ok {
+			continue
+		}
+		result.Removed = append(result.Removed, DiffEntry{
+			Path:     p,
+			DestETag: aws.ToString(d.ETag),
+			DestSize: aws.ToInt64(d.Size),
+		})
+	}
+	for _, entries := range [][]DiffEntry{result.Added, result.Removed, result.Changed} {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Path < entries[j].Path
+		})
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package s3fs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestDiffObjects(t *testing.T) {
+	object := func(etag string, size int64) types.Object {
+		return types.Object{ETag: aws.String(etag), Size: aws.Int64(size)}
+	}
+	src := map[string]types.Object{
+		"same":    object("a", 1),
+		"changed": object("b", 1),
+		"resized": object("c", 1),
+		"added":   object("d", 1),
+	}
+	dst := map[string]types.Object{
+		"same":    object("a", 1),
+		"changed": object("x", 1),
+		"resized": object("c", 2),
+		"removed": object("e", 1),
+	}
+	diff := diffObjects(src, dst)
+
+	if len(diff.Added) != 1 || diff.Added[0].Path != "added" {
+		t.Fatal("invalid added entries:", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Path != "removed" {
+		t.Fatal("invalid removed entries:", diff.Removed)
+	}
+	if len(diff.Changed) != 2 {
+		t.Fatal("invalid changed entries:", diff.Changed)
+	}
+	if diff.Changed[0].Path != "changed" || diff.Changed[1].Path != "resized" {
+		t.Fatal("invalid changed order:", diff.Changed)
+	}
+	if diff.Changed[1].SrcSize != 1 || diff.Changed[1].DestSize != 2 {
+		t.Fatal("invalid changed size:", diff.Changed[1])
+	}
+}
@@ -0,0 +1,107 @@
+package s3fs
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func (s3fs *S3FS) GetTags(key string) (map[string]string, error) {
+	output, err := s3fs.s3.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tagMap(output.TagSet), nil
+}
+
+// SetTags replaces every tag of key with tags.
+func (s3fs *S3FS) SetTags(key string, tags map[string]string) error {
+	_, err := s3fs.s3.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(key)),
+		Tagging: &types.Tagging{
+			TagSet: tagSet(tags),
+		},
+	})
+	return err
+}
+
+func (s3fs *S3FS) DeleteTags(key string) error {
+	_, err := s3fs.s3.DeleteObjectTagging(ctx, &s3.DeleteObjectTaggingInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+		Key:    aws.String(s3fs.getKey(key)),
+	})
+	return err
+}
+
+// ListByTags walks prefix and returns the files carrying every tag in
+// filter, sorted by path.
+func (s3fs *S3FS) ListByTags(prefix string, filter map[string]string) (*[]FileInfo, error) {
+	fileList := make([]FileInfo, 0)
+	mu := &sync.Mutex{}
+	g := &group{}
+	sem := make(chan struct{}, requestConcurrency)
+
+	err := s3fs.Walk(prefix, func(info FileInfo) error {
+		if info.Type != File {
+			return nil
+		}
+		// Stop walking once a lookup has failed.
+		if err := g.Err(); err != nil {
+			return err
+		}
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			tags, err := s3fs.GetTags(info.Path)
+			if err != nil {
+				return err
+			}
+			if matchTags(tags, filter) {
+				mu.Lock()
+				fileList = append(fileList, info)
+				mu.Unlock()
+			}
+			return nil
+		})
+		return nil
+	})
+	if e := g.Wait(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(fileList, func(i, j int) bool {
+		return fileList[i].Path < fileList[j].Path
+	})
+	return &fileList, nil
+}
+
+func matchTags(tags map[string]string, filter map[string]string) bool {
+	for k, v := range filter {
+		if value, ok := tags[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeTags encodes tags as the URL query string S3 expects in the
+// x-amz-tagging header.
+func encodeTags(tags map[string]string) string {
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return strings.ReplaceAll(values.Encode(), "+", "%20")
+}
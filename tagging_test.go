@@ -0,0 +1,34 @@
+package s3fs
+
+import (
+	"testing"
+)
+
+func TestMatchTags(t *testing.T) {
+	tags := map[string]string{
+		"class": "secret",
+		"team":  "storage",
+	}
+	if !matchTags(tags, map[string]string{"class": "secret"}) {
+		t.Fatal("tags should match")
+	}
+	if matchTags(tags, map[string]string{"class": "public"}) {
+		t.Fatal("tags should not match a different value")
+	}
+	if matchTags(tags, map[string]string{"owner": ""}) {
+		t.Fatal("tags should not match a missing key")
+	}
+	if !matchTags(tags, nil) {
+		t.Fatal("empty filter should match")
+	}
+}
+
+func TestEncodeTags(t *testing.T) {
+	encoded := encodeTags(map[string]string{
+		"team":  "storage ops",
+		"class": "a&b",
+	})
+	if encoded != "class=a%26b&team=storage%20ops" {
+		t.Fatal("invalid encoding:", encoded)
+	}
+}
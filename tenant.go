@@ -0,0 +1,44 @@
+package s3fs
+
+import (
+	"strings"
+)
+
+// WithDomain returns a view of s3fs scoped to domain. The view shares the
+// S3 client of s3fs and can be created per request.
+func (s3fs *S3FS) WithDomain(domain string) *S3FS {
+	config := *s3fs.config
+	config.Domain = domain
+	return s3fs.view(&config)
+}
+
+// WithNameSpace returns a view of s3fs scoped to nameSpace, keeping the
+// domain. The view shares the S3 client of s3fs.
+func (s3fs *S3FS) WithNameSpace(nameSpace string) *S3FS {
+	config := *s3fs.config
+	config.NameSpace = nameSpace
+	return s3fs.view(&config)
+}
+
+// Sub returns a view of s3fs rooted at dir, so that "/" of the view is dir
+// of s3fs. The view shares the S3 client of s3fs.
+func (s3fs *S3FS) Sub(dir string) *S3FS {
+	config := *s3fs.config
+	dir = strings.Trim(dir, "/")
+	if dir != "" {
+		if config.Domain != "" {
+			config.Domain += "/" + dir
+		} else {
+			config.Domain = dir
+		}
+	}
+	return s3fs.view(&config)
+}
+
+func (s3fs *S3FS) view(config *Config) *S3FS {
+	return &S3FS{
+		s3fs.s3,
+		config,
+		s3fs.profile,
+	}
+}
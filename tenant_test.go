@@ -0,0 +1,43 @@
+package s3fs
+
+import (
+	"testing"
+)
+
+func TestViews(t *testing.T) {
+	base := &S3FS{config: &Config{NameSpace: "app", Bucket: "bucket"}}
+	t.Run("with domain", func(st *testing.T) {
+		view := base.WithDomain("tenant")
+		if key := view.getKey("/a"); key != "app/tenant/a" {
+			st.Fatal("key error:", key)
+		}
+		if base.config.Domain != "" {
+			st.Fatal("base config changed:", base.config.Domain)
+		}
+		if view.s3 != base.s3 || view.config.Bucket != "bucket" {
+			st.Fatal("view should share the client and bucket")
+		}
+	})
+	t.Run("with namespace", func(st *testing.T) {
+		view := base.WithDomain("tenant").WithNameSpace("other")
+		if key := view.getKey("/a"); key != "other/tenant/a" {
+			st.Fatal("key error:", key)
+		}
+	})
+	t.Run("sub", func(st *testing.T) {
+		view := base.Sub("/dir/")
+		if key := view.getKey("/a"); key != "app/dir/a" {
+			st.Fatal("key error:", key)
+		}
+		nested := base.WithDomain("tenant").Sub("dir").Sub("nested")
+		if key := nested.getKey("/a"); key != "app/tenant/dir/nested/a" {
+			st.Fatal("key error:", key)
+		}
+		if path := nested.pathOf("app/tenant/dir/nested/a"); path != "/a" {
+			st.Fatal("path error:", path)
+		}
+		if base.Sub("/").getKey("/a") != base.getKey("/a") {
+			st.Fatal("root sub should be the same root")
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package s3fs
+
+import (
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type (
+	VersionInfo struct {
+		Name           string    `json:"name"`
+		Path           string    `json:"path"`
+		VersionID      string    `json:"versionId"`
+		IsLatest       bool      `json:"isLatest"`
+		IsDeleteMarker bool      `json:"isDeleteMarker,omitempty"`
+		Size           int64     `json:"size,omitempty"`
+		ETag           string    `json:"etag,omitempty"`
+		LastModified   time.Time `json:"lastModified"`
+	}
+)
+
+func (s3fs *S3FS) EnableVersioning() error {
+	return s3fs.putVersioning(s3fs.config.Bucket, types.BucketVersioningStatusEnabled)
+}
+
+func (s3fs *S3FS) SuspendVersioning() error {
+	return s3fs.putVersioning(s3fs.config.Bucket, types.BucketVersioningStatusSuspended)
+}
+
+func (s3fs *S3FS) VersioningEnabled() (bool, error) {
+	output, err := s3fs.s3.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{
+		Bucket: aws.String(s3fs.config.Bucket),
+	})
+	if err != nil {
+		return false, err
+	}
+	return output.Status == types.BucketVersioningStatusEnabled, nil
+}
+
+func (s3fs *S3FS) putVersioning(bucket string, status types.BucketVersioningStatus) error {
+	_, err := s3fs.s3.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucket),
+		VersioningConfiguration: &types.VersioningConfiguration{
+			Status: status,
+		},
+	})
+	return err
+}
+
+// ListVersions returns every version and delete marker of key, newest first.
+func (s3fs *S3FS) ListVersions(key string) (*[]VersionInfo, error) {
+	path := s3fs.pathOf(s3fs.getKey(key))
+	versions := make([]VersionInfo, 0)
+	err := s3fs.listVersions(key, func(v VersionInfo) error {
+		if v.Path == path {
+			versions = append(versions, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortVersions(versions)
+	return &versions, nil
+}
+
+func (s3fs *S3FS) GetVersion(key string, versionID string) (*io.ReadCloser, error) {
+	output, err := s3fs.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(s3fs.config.Bucket),
+		Key:       aws.String(s3fs.getKey(key)),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &output.Body, nil
+}
+
+func (s3fs *S3FS) InfoVersion(key string, versionID string) *s3.HeadObjectOutput {
+	result, _ := s3fs.s3.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:    aws.String(s3fs.config.Bucket),
+		Key:       aws.String(s3fs.getKey(key)),
+		VersionId: aws.String(versionID),
+	})
+	return result
+}
+
+// DeleteVersion permanently removes a single version or delete marker.
+func (s3fs *S3FS) DeleteVersion(key string, versionID string) error {
+	_, err := s3fs.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:    aws.String(s3fs.config.Bucket),
+		Key:       aws.String(s3fs.getKey(key)),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return s3fs.deleteError(s3fs.getKey(key), versionID, err)
+	}
+	return nil
+}
+
+// RestoreVersion makes versionID the current version of key by copying it
+// over the current one. The old version is kept in the history.
+func (s3fs *S3FS) RestoreVersion(key string, versionID string) error {
+	_, err := s3fs.s3.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(s3fs.config.Bucket),
+		CopySource: aws.String(url.QueryEscape(s3fs.config.Bucket+"/"+s3fs.getKey(key)) + "?versionId=" + url.QueryEscape(versionID)),
+		Key:        aws.String(s3fs.getKey(key)),
+	})
+	return err
+}
+
+func (s3fs *S3FS) listVersions(prefix string, fn func(VersionInfo) error) error {
+	var keyMarker, versionIDMarker *string
+	for {
+		list, err := s3fs.s3.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:          aws.String(s3fs.config.Bucket),
+			Prefix:          aws.String(s3fs.getKey(prefix)),
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionIDMarker,
+		})
+		if err != nil {
+			return err
+		}
+		for _, val := range list.Versions {
+			if err := fn(VersionInfo{
+				Name:         nameOf(*val.Key),
+				Path:         s3fs.pathOf(*val.Key),
+				VersionID:    aws.ToString(val.VersionId),
+				IsLatest:     aws.ToBool(val.IsLatest),
+				Size:         aws.ToInt64(val.Size),
+				ETag:         aws.ToString(val.ETag),
+				LastModified: aws.ToTime(val.LastModified),
+			}); err != nil {
+				return err
+			}
+		}
+		for _, val := range list.DeleteMarkers {
+			if err := fn(VersionInfo{
+				Name:           nameOf(*val.Key),
+				Path:           s3fs.pathOf(*val.Key),
+				VersionID:      aws.ToString(val.VersionId),
+				IsLatest:       aws.ToBool(val.IsLatest),
+				IsDeleteMarker: true,
+				LastModified:   aws.ToTime(val.LastModified),
+			}); err != nil {
+				return err
+			}
+		}
+
+		if !aws.ToBool(list.IsTruncated) {
+			return nil
+		}
+		keyMarker = list.NextKeyMarker
+		versionIDMarker = list.NextVersionIdMarker
+	}
+}
+
+// pathOf converts an S3 object key into an S3FS path.
+func (s3fs *S3FS) pathOf(key string) string {
+	return "/" + strings.TrimPrefix(key, s3fs.getKey(""))
+}
+
+func nameOf(key string) string {
+	k := strings.Split(strings.TrimSuffix(key, "/"), "/")
+	return k[len(k)-1]
+}
+
+// sortVersions orders versions newest first, keeping the latest version
+// ahead of older ones sharing its timestamp.
+func sortVersions(versions []VersionInfo) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if !versions[i].LastModified.Equal(versions[j].LastModified) {
+			return versions[i].LastModified.After(versions[j].LastModified)
+		}
+		return versions[i].IsLatest && !versions[j].IsLatest
+	})
+}
@@ -0,0 +1,32 @@
+package s3fs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSortVersions(t *testing.T) {
+	now := time.Now()
+	versions := []VersionInfo{
+		{VersionID: "old", LastModified: now.Add(-time.Hour)},
+		{VersionID: "noncurrent", LastModified: now},
+		{VersionID: "latest", LastModified: now, IsLatest: true},
+		{VersionID: "older", LastModified: now.Add(-2 * time.Hour)},
+	}
+	sortVersions(versions)
+
+	for i, id := range []string{"latest", "noncurrent", "old", "older"} {
+		if versions[i].VersionID != id {
+			t.Fatal("invalid order:", i, versions[i].VersionID)
+		}
+	}
+}
+
+func TestNameOf(t *testing.T) {
+	if name := nameOf("ns/domain/dir/file"); name != "file" {
+		t.Fatal("invalid file name:", name)
+	}
+	if name := nameOf("ns/domain/dir/"); name != "dir" {
+		t.Fatal("invalid dir name:", name)
+	}
+}